@@ -0,0 +1,54 @@
+package gositemap
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// XDefault 用于没有匹配语言时展示的网页
+const XDefault = "x-default"
+
+// 网页的其他语言版本 <xhtml:link rel="alternate" hreflang="en" href="..."/>
+// 每个语言版本都需要列出包括自身在内的全部语言版本
+type alternate struct {
+	XMLName  xml.Name `xml:"xhtml:link"`
+	Rel      string   `xml:"rel,attr"`
+	Hreflang string   `xml:"hreflang,attr"`
+	Href     string   `xml:"href,attr"`
+}
+
+// hreflang 为 ISO 639-1 语言代码，可以加上 ISO 3166-1 地区代码，如 en、en-GB、zh-Hans-CN，或者 x-default
+func NewAlternate(hreflang, href string) *alternate {
+	return &alternate{
+		Rel:      "alternate",
+		Hreflang: hreflang,
+		Href:     href,
+	}
+}
+
+// 生成一组互相引用的语言版本，pages 的键为 hreflang，值为对应语言的网址
+// 每个不同的网址生成一个 url，按照 hreflang 排序，每个 url 都包含全部语言版本
+func NewAlternateCluster(pages map[string]string) []*url {
+	hreflangs := make([]string, 0, len(pages))
+	for hreflang := range pages {
+		hreflangs = append(hreflangs, hreflang)
+	}
+	sort.Strings(hreflangs)
+	var (
+		urls []*url
+		seen = make(map[string]bool)
+	)
+	for _, hreflang := range hreflangs {
+		href := pages[hreflang]
+		if seen[href] {
+			continue
+		}
+		seen[href] = true
+		u := NewUrl().SetLoc(href)
+		for _, h := range hreflangs {
+			u.AppendAlternate(NewAlternate(h, pages[h]))
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
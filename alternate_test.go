@@ -0,0 +1,81 @@
+package gositemap
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewAlternateCluster(t *testing.T) {
+	urls := NewAlternateCluster(map[string]string{
+		"en":      "https://www.douyacun.com/en/",
+		"zh-Hans": "https://www.douyacun.com/zh/",
+		XDefault:  "https://www.douyacun.com/en/",
+	})
+	if len(urls) != 2 || urls[0].Loc != "https://www.douyacun.com/en/" || urls[1].Loc != "https://www.douyacun.com/zh/" {
+		t.Fatalf("unexpected cluster %+v", urls)
+	}
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	for _, u := range urls {
+		if len(u.Token) != 3 {
+			t.Fatalf("expected 3 alternates, got %d", len(u.Token))
+		}
+		st.AppendUrl(u)
+	}
+	if err := st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`xmlns:xhtml="http://www.w3.org/1999/xhtml"`,
+		`<xhtml:link rel="alternate" hreflang="zh-Hans" href="https://www.douyacun.com/zh/"></xhtml:link>`,
+		`<xhtml:link rel="alternate" hreflang="x-default" href="https://www.douyacun.com/en/"></xhtml:link>`,
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Fatalf("missing %s in\n%s", s, data)
+		}
+	}
+
+	parsed, err := ParseSiteMap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := parsed.Token[1].(*url).Token[2].(*alternate); a.Hreflang != "zh-Hans" || a.Href != "https://www.douyacun.com/zh/" {
+		t.Fatalf("unexpected alternate %+v", a)
+	}
+}
+
+func TestAlternate_Validate(t *testing.T) {
+	for hreflang, valid := range map[string]bool{
+		"en":         true,
+		"en-GB":      true,
+		"en-gb":      true,
+		"zh-Hant-TW": true,
+		"x-default":  true,
+		"english":    false,
+		"zho":        false,
+		"zho-CN":     false,
+		"en-XX":      false,
+		"en-Latn-":   false,
+		"":           false,
+	} {
+		if isHreflang(hreflang) != valid {
+			t.Errorf("%q: expected valid=%v", hreflang, valid)
+		}
+	}
+
+	u := NewUrl().SetLoc("https://www.douyacun.com/en/")
+	u.AppendAlternate(NewAlternate("en", "https://www.douyacun.com/en/"))
+	u.AppendAlternate(NewAlternate("EN", "https://www.douyacun.com/en/"))
+	u.AppendAlternate(NewAlternate("fr", "/fr/"))
+	var errs ValidationErrors
+	if err := u.Validate(); !errors.As(err, &errs) || len(errs) != 2 ||
+		errs[0].Err != DuplicateHreflangError || errs[1].Err != InvalidLocError {
+		t.Fatalf("unexpected errors %v", err)
+	}
+}
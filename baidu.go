@@ -0,0 +1,166 @@
+package gositemap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+const (
+	// BaiduMaxSitemapBytes defines max uncompressed bytes per sitemap accepted by Baidu
+	BaiduMaxSitemapBytes = 10 * 1024 * 1024
+	// BaiduPushEndpoint defines default endpoint of Baidu link submission API
+	BaiduPushEndpoint = "http://data.zz.baidu.com/urls"
+	// MaxBaiduPushUrls defines max urls per Baidu push request
+	MaxBaiduPushUrls = 2000
+)
+
+// 输出规范，决定单个文件的限制和扩展的标记
+type Profile int
+
+const (
+	// sitemaps.org 协议，Google、Bing 等搜索引擎使用
+	DefaultProfile Profile = iota
+	// 百度：单个文件不超过 10MB，移动适配使用百度的 mobile 命名空间
+	BaiduProfile
+)
+
+// 单个文件未压缩时的最大字节数
+func (p Profile) maxBytes() int {
+	if p == BaiduProfile {
+		return BaiduMaxSitemapBytes
+	}
+	return MaxSitemapBytes
+}
+
+// urlset 上实际声明的命名空间，百度规范下 <mobile:mobile> 使用百度的命名空间
+func (p Profile) namespaces(used xmlns) xmlns {
+	if p == BaiduProfile && used&MobileXmlNS != 0 {
+		used = used&^MobileXmlNS | BaiduMobileXmlNS
+	}
+	return used
+}
+
+// 百度移动适配的网页类型，<mobile:mobile type="..."/>
+type MobileType string
+
+const (
+	// 移动网页
+	MobileOnly MobileType = "mobile"
+	// 响应式网页，同时适合PC和移动设备
+	PcMobile MobileType = "pc,mobile"
+	// 代码适配，同一网址根据设备返回不同的网页
+	HtmlAdapt MobileType = "htmladapt"
+)
+
+// 百度普通收录的主动推送（API提交）
+// 文档：https://ziyuan.baidu.com/linksubmit/index
+type baiduPush struct {
+	client   *http.Client
+	endpoint string
+	site     string
+	token    string
+}
+
+// site 为在百度搜索资源平台验证过的站点，如 https://www.douyacun.com
+func NewBaiduPush(site, token string) *baiduPush {
+	return &baiduPush{
+		client:   http.DefaultClient,
+		endpoint: BaiduPushEndpoint,
+		site:     site,
+		token:    token,
+	}
+}
+
+func (p *baiduPush) SetClient(client *http.Client) *baiduPush {
+	p.client = client
+	return p
+}
+
+// 推送地址，默认为 http://data.zz.baidu.com/urls
+func (p *baiduPush) SetEndpoint(endpoint string) *baiduPush {
+	p.endpoint = endpoint
+	return p
+}
+
+// 推送结果
+type BaiduPushResult struct {
+	// 成功推送的网址数量
+	Success int `json:"success"`
+	// 当天剩余的可推送网址数量
+	Remain int `json:"remain"`
+	// 不是本站的网址
+	NotSameSite []string `json:"not_same_site"`
+	// 不合法的网址
+	NotValid []string `json:"not_valid"`
+}
+
+// 推送失败时百度返回的错误，如 401 token is not valid
+type BaiduPushError struct {
+	Code    int    `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *BaiduPushError) Error() string {
+	return fmt.Sprintf("百度推送失败：%d %s", e.Code, e.Message)
+}
+
+// 推送网址，每次请求最多 2000 个网址，返回所有请求合并后的结果
+// 当天的配额用完时停止推送，返回已推送部分的结果
+func (p *baiduPush) Push(ctx context.Context, urls []string) (*BaiduPushResult, error) {
+	result := &BaiduPushResult{}
+	for len(urls) > 0 {
+		batch := urls
+		if len(batch) > MaxBaiduPushUrls {
+			batch = batch[:MaxBaiduPushUrls]
+		}
+		urls = urls[len(batch):]
+		r, err := p.push(ctx, batch)
+		if err != nil {
+			return result, err
+		}
+		result.Success += r.Success
+		result.Remain = r.Remain
+		result.NotSameSite = append(result.NotSameSite, r.NotSameSite...)
+		result.NotValid = append(result.NotValid, r.NotValid...)
+		if r.Remain <= 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (p *baiduPush) push(ctx context.Context, urls []string) (*BaiduPushResult, error) {
+	query := neturl.Values{}
+	query.Set("site", p.site)
+	query.Set("token", p.token)
+	body := strings.Join(urls, "\n")
+	req, err := http.NewRequest(http.MethodPost, p.endpoint+"?"+query.Encode(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := &BaiduPushError{Code: resp.StatusCode, Message: resp.Status}
+		_ = json.Unmarshal(data, e)
+		return nil, e
+	}
+	result := &BaiduPushResult{}
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
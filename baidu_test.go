@@ -0,0 +1,148 @@
+package gositemap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSitemap_BaiduProfile(t *testing.T) {
+	st := NewSiteMap()
+	st.SetMaxBytes(20 * 1024 * 1024)
+	st.SetProfile(BaiduProfile)
+	if st.maxBytes != BaiduMaxSitemapBytes {
+		t.Fatalf("maxBytes %d, want %d", st.maxBytes, BaiduMaxSitemapBytes)
+	}
+	st.SetMaxBytes(20 * 1024 * 1024)
+	if st.maxBytes != BaiduMaxSitemapBytes {
+		t.Fatalf("maxBytes %d exceeds baidu limit", st.maxBytes)
+	}
+
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/").SetMobileType(PcMobile))
+	st.AppendUrl(NewUrl().SetLoc("https://m.douyacun.com/").SetMobile(true))
+	data, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `xmlns:mobile="`+BaiduMobileNamespace+`"`) ||
+		strings.Contains(string(data), MobileNamespace) ||
+		!strings.Contains(string(data), `<mobile:mobile type="pc,mobile">`) {
+		t.Fatalf("unexpected xml %s", data)
+	}
+
+	parsed, err := ParseSiteMap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := parsed.Token[0].(*url).Token[0].(*mobileMarker); !ok || m.Type != PcMobile {
+		t.Fatalf("unexpected parsed url %+v", parsed.Token[0])
+	}
+
+	txt, err := st.ToTxt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(txt) != "https://www.douyacun.com/\nhttps://m.douyacun.com/\n" {
+		t.Fatalf("unexpected txt %q", txt)
+	}
+	st.SetMaxLinks(1)
+	if _, err = st.ToTxt(); err != TooMuchLinksError {
+		t.Fatalf("expect TooMuchLinksError, got %v", err)
+	}
+}
+
+func TestStreamSiteMap_BaiduProfile(t *testing.T) {
+	storage := NewMemoryStorage()
+	st := NewStreamSiteMap()
+	st.SetStorage(storage)
+	st.SetProfile(BaiduProfile)
+	if err := st.AppendUrl(NewUrl().SetLoc("https://m.douyacun.com/").SetMobileType(MobileOnly)); err != nil {
+		t.Fatal(err)
+	}
+	filename, err := st.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := readFile(storage, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `xmlns:mobile="`+BaiduMobileNamespace+`"`) ||
+		strings.Contains(string(data), MobileNamespace) {
+		t.Fatalf("unexpected xml %s", data)
+	}
+}
+
+func TestBaiduPush_Push(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Query().Get("token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":401,"message":"token is not valid"}`))
+			return
+		}
+		if r.URL.Query().Get("site") != "https://www.douyacun.com" || r.Header.Get("Content-Type") != "text/plain" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":400,"message":"site error"}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		lines := strings.Split(string(body), "\n")
+		result := BaiduPushResult{NotSameSite: []string{}, NotValid: []string{}}
+		for _, line := range lines {
+			if strings.HasPrefix(line, "https://www.douyacun.com/") {
+				result.Success++
+			} else {
+				result.NotSameSite = append(result.NotSameSite, line)
+			}
+		}
+		result.Remain = 5000 - result.Success
+		batches = append(batches, lines)
+		_ = json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	var urls []string
+	for i := 0; i < MaxBaiduPushUrls; i++ {
+		urls = append(urls, fmt.Sprintf("https://www.douyacun.com/%d", i))
+	}
+	urls = append(urls, "https://m.douyacun.com/")
+	push := NewBaiduPush("https://www.douyacun.com", "secret").SetEndpoint(server.URL)
+	result, err := push.Push(context.Background(), urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0]) != MaxBaiduPushUrls || len(batches[1]) != 1 {
+		t.Fatalf("unexpected batches %d", len(batches))
+	}
+	if result.Success != MaxBaiduPushUrls || len(result.NotSameSite) != 1 || result.NotSameSite[0] != "https://m.douyacun.com/" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	_, err = NewBaiduPush("https://www.douyacun.com", "wrong").SetEndpoint(server.URL).Push(context.Background(), urls[:1])
+	if e, ok := err.(*BaiduPushError); !ok || e.Code != 401 || e.Message != "token is not valid" {
+		t.Fatalf("expect BaiduPushError, got %v", err)
+	}
+
+	// 通过 notifier 在 Storage 后推送
+	batches = nil
+	n := NewNotifier().SetBaiduPush(push)
+	if err = n.Submit(context.Background(), urls[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("unexpected batches %v", batches)
+	}
+}
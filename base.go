@@ -1,20 +1,40 @@
 package gositemap
 
-type xmlns int8
+// 每个命名空间占一位，内置的扩展之外可以通过 RegisterNamespace 注册
+type xmlns uint64
 
 const (
-	ImageXmlNS xmlns = 1
-	VideoXmlNS xmlns = 2
-	NewsXmlNS  xmlns = 4
+	ImageXmlNS  xmlns = 1
+	VideoXmlNS  xmlns = 2
+	NewsXmlNS   xmlns = 4
+	XhtmlXmlNS  xmlns = 8
+	MobileXmlNS xmlns = 16
+	// 百度的 mobile 命名空间，BaiduProfile 下代替 MobileXmlNS 声明
+	BaiduMobileXmlNS xmlns = 32
+)
+
+const (
+	SitemapNamespace     = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	ImageNamespace       = "http://www.google.com/schemas/sitemap-image/1.1"
+	VideoNamespace       = "http://www.google.com/schemas/sitemap-video/1.1"
+	NewsNamespace        = "http://www.google.com/schemas/sitemap-news/0.9"
+	XhtmlNamespace       = "http://www.w3.org/1999/xhtml"
+	MobileNamespace      = "http://www.google.com/schemas/sitemap-mobile/1.0"
+	BaiduMobileNamespace = "http://www.baidu.com/schemas/sitemap-mobile/1/"
+	AtomNamespace        = "http://www.w3.org/2005/Atom"
 )
 
 type base struct {
 	xmlns xmlns
 }
 
-// image: 0001
-// video: 0010
-//  news: 0100
+// image: 00001
+// video: 00010
+// news: 00100
+// xhtml: 01000
+// mobile: 10000
+// baidu mobile: 100000
+// 自定义扩展依次使用后面的位
 func (b *base) setNs(xmlns xmlns) {
 	b.xmlns = b.xmlns | xmlns
 }
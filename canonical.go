@@ -0,0 +1,210 @@
+package gositemap
+
+import (
+	"encoding/xml"
+	neturl "net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 网址规范化的规则，可以组合使用，见 SetCanonical
+// 设置了任意规则时，AppendUrl 会合并规范化之后 loc 相同的网址
+type Canonical int
+
+const (
+	// 只合并 loc 完全相同的网址，不修改 loc
+	MergeDuplicates Canonical = 1 << iota
+	// scheme 和域名转换为小写
+	LowerHost
+	// 去掉 http 的 80 端口和 https 的 443 端口
+	StripDefaultPort
+	// 去掉 # 之后的部分
+	StripFragment
+	// 去掉路径末尾的 /，没有路径时补上 /
+	TrimTrailingSlash
+	// 查询参数按照名称排序，同名参数保持原来的顺序，去掉空的 ?
+	SortQuery
+	// 以上所有规则
+	CanonicalAll = MergeDuplicates | LowerHost | StripDefaultPort | StripFragment | TrimTrailingSlash | SortQuery
+)
+
+// 规范化之后的网址，无法解析或者不是绝对地址时原样返回
+func (c Canonical) apply(loc string) string {
+	if c&^MergeDuplicates == 0 {
+		return loc
+	}
+	u, err := neturl.Parse(loc)
+	if err != nil || u.Host == "" {
+		return loc
+	}
+	if c&LowerHost != 0 {
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+	}
+	if c&StripDefaultPort != 0 {
+		scheme, port := strings.ToLower(u.Scheme), u.Port()
+		if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+			u.Host = strings.TrimSuffix(u.Host, ":"+port)
+		}
+	}
+	if c&StripFragment != 0 {
+		u.Fragment = ""
+	}
+	if c&TrimTrailingSlash != 0 {
+		// 在编码后的路径上去掉 /，保留 %2F 这样转义的 /
+		p := trimTrailingSlash(u.EscapedPath())
+		if path, err := neturl.PathUnescape(p); err == nil {
+			u.Path, u.RawPath = path, p
+		}
+	}
+	if c&SortQuery != 0 {
+		if u.RawQuery != "" {
+			u.RawQuery = sortQuery(u.RawQuery)
+		}
+		u.ForceQuery = u.ForceQuery && u.RawQuery != ""
+	}
+	return u.String()
+}
+
+// /a/ => /a，空路径 => /
+func trimTrailingSlash(p string) string {
+	if p = strings.TrimRight(p, "/"); p == "" {
+		return "/"
+	}
+	return p
+}
+
+// 按照参数名排序，不重新编码
+func sortQuery(query string) string {
+	var params []string
+	for _, param := range strings.Split(query, "&") {
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+	key := func(param string) string {
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			return param[:i]
+		}
+		return param
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return key(params[i]) < key(params[j])
+	})
+	return strings.Join(params, "&")
+}
+
+// 规范化还没有加入索引的网址，如 SetCanonical 之前添加的网址，并与已有的网址合并，需要持有 s.mu
+// loc 变化的网址替换为副本，原来的网址不会被修改，已经生成的副本不受影响
+func (s *urlSet) canonicalize(canonical Canonical) {
+	if s.index == nil {
+		s.index = make(map[string]int)
+	}
+	for s.indexed < len(s.Token) {
+		u := s.Token[s.indexed].(*url)
+		if loc := canonical.apply(u.Loc); loc != u.Loc {
+			c := *u
+			c.Loc = loc
+			u = &c
+			s.Token[s.indexed] = u
+			s.version++
+		}
+		if i, ok := s.index[u.Loc]; ok {
+			s.Token[i] = s.Token[i].(*url).merge(u)
+			s.Token = append(s.Token[:s.indexed], s.Token[s.indexed+1:]...)
+			s.version++
+			continue
+		}
+		s.index[u.Loc] = s.indexed
+		s.indexed++
+	}
+}
+
+// 查找与 u 规范化之后 loc 相同的网址并合并，u.Loc 已经规范化，需要持有 s.mu
+// 合并后的网址替换原来的位置，原来的网址不会被修改，已经生成的副本不受影响
+func (s *urlSet) merge(u *url, canonical Canonical) bool {
+	s.canonicalize(canonical)
+	i, ok := s.index[u.Loc]
+	if !ok {
+		return false
+	}
+	s.Token[i] = s.Token[i].(*url).merge(u)
+	return true
+}
+
+// 合并两个相同网页的网址：lastmod 取较新的一个，图片和视频取并集，
+// 其他字段和扩展以原来的网址为准，原来没有时使用 other 的
+func (u *url) merge(other *url) *url {
+	merged := *u
+	merged.base = &base{xmlns: u.xmlns | other.xmlns}
+	merged.Token = append([]xml.Token(nil), u.Token...)
+	merged.errs = append(append(ValidationErrors(nil), u.errs...), other.errs...)
+
+	if newer, err := parseW3CDate(other.LastMod); err == nil {
+		if current, err := parseW3CDate(u.LastMod); err != nil || newer.After(current) {
+			merged.LastMod = other.LastMod
+		}
+	}
+	if merged.ChangeFreq == "" {
+		merged.ChangeFreq = other.ChangeFreq
+	}
+	if merged.Priority == 0 {
+		merged.Priority = other.Priority
+	}
+
+	var (
+		images     = make(map[string]bool)
+		videos     = make(map[string]bool)
+		hreflangs  = make(map[string]bool)
+		extensions = make(map[reflect.Type]bool)
+	)
+	for _, token := range u.Token {
+		switch t := token.(type) {
+		case *image:
+			images[t.Loc] = true
+		case *video:
+			videos[t.key()] = true
+		case *alternate:
+			hreflangs[strings.ToLower(t.Hreflang)] = true
+		default:
+			extensions[reflect.TypeOf(token)] = true
+		}
+	}
+	for _, token := range other.Token {
+		switch t := token.(type) {
+		case *image:
+			if images[t.Loc] || len(images) >= MaxImagesPerUrl {
+				continue
+			}
+			images[t.Loc] = true
+		case *video:
+			if videos[t.key()] {
+				continue
+			}
+			videos[t.key()] = true
+		case *alternate:
+			if hreflangs[strings.ToLower(t.Hreflang)] {
+				continue
+			}
+			hreflangs[strings.ToLower(t.Hreflang)] = true
+		default:
+			if extensions[reflect.TypeOf(token)] {
+				continue
+			}
+		}
+		merged.Token = append(merged.Token, token)
+	}
+	return &merged
+}
+
+// 判断两个视频是否相同，依次使用 content_loc、player_loc、thumbnail_loc 和标题
+func (v *video) key() string {
+	if v.ContentLoc != "" {
+		return v.ContentLoc
+	}
+	if v.PlayerLoc != nil && v.PlayerLoc.Content != "" {
+		return v.PlayerLoc.Content
+	}
+	return v.ThumbnailLoc + "\n" + v.Title
+}
@@ -0,0 +1,150 @@
+package gositemap
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCanonical_Apply(t *testing.T) {
+	for loc, want := range map[string]string{
+		"HTTPS://WWW.Douyacun.COM:443/a/?b=2&a=1#top": "https://www.douyacun.com/a?a=1&b=2",
+		"http://www.douyacun.com:80":                  "http://www.douyacun.com/",
+		"http://www.douyacun.com:8080/a//":            "http://www.douyacun.com:8080/a",
+		"https://www.douyacun.com/?b=1&a=2&b=0":       "https://www.douyacun.com/?a=2&b=1&b=0",
+		"https://www.douyacun.com/a%2Fb/":             "https://www.douyacun.com/a%2Fb",
+		"https://www.douyacun.com/a%2F/":              "https://www.douyacun.com/a%2F",
+		"https://www.douyacun.com/?":                  "https://www.douyacun.com/",
+		"https://www.douyacun.com/a?&":                "https://www.douyacun.com/a",
+		"https://www.douyacun.com/Path/":              "https://www.douyacun.com/Path",
+		"/relative/":                                  "/relative/",
+	} {
+		if got := CanonicalAll.apply(loc); got != want {
+			t.Fatalf("apply(%q) = %q, want %q", loc, got, want)
+		}
+	}
+	if got := MergeDuplicates.apply("HTTPS://WWW.Douyacun.COM/a/#top"); got != "HTTPS://WWW.Douyacun.COM/a/#top" {
+		t.Fatalf("MergeDuplicates should not change loc, got %q", got)
+	}
+}
+
+func TestSitemap_SetCanonical(t *testing.T) {
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.AppendUrl(NewUrl().SetLoc("/before/"))
+	st.SetCanonical(CanonicalAll)
+
+	older := time.Date(2020, 4, 18, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2020, 4, 19, 0, 0, 0, 0, time.UTC)
+	first := NewUrl().SetLoc("https://www.douyacun.com/post/?b=2&a=1").SetLastmod(newer).SetChangefreq(Daily)
+	first.AppendImage(NewImage().SetLoc("https://www.douyacun.com/1.jpg"))
+	first.AppendVideo(NewVideo().SetThumbnailLoc("https://www.douyacun.com/v.jpg").SetTitle("v").SetContentLoc("https://www.douyacun.com/v.mp4"))
+	st.AppendUrl(first)
+	st.AppendUrl(NewUrl().SetLoc("/other.html"))
+
+	// 同一网页的不同写法
+	second := NewUrl().SetLoc("https://WWW.douyacun.com:443/post?a=1&b=2#comments").SetLastmod(older).SetPriority(0.8)
+	second.AppendImage(NewImage().SetLoc("https://www.douyacun.com/1.jpg"))
+	second.AppendImage(NewImage().SetLoc("https://www.douyacun.com/2.jpg"))
+	second.AppendVideo(NewVideo().SetThumbnailLoc("https://www.douyacun.com/v.jpg").SetTitle("v").SetContentLoc("https://www.douyacun.com/v.mp4"))
+	second.AppendVideo(NewVideo().SetThumbnailLoc("https://www.douyacun.com/w.jpg").SetTitle("w").SetContentLoc("https://www.douyacun.com/w.mp4"))
+	snapshot := st.snapshot()
+	st.AppendUrl(second)
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/before").SetLastmod(newer))
+
+	if len(st.Token) != 3 {
+		t.Fatalf("expect 3 urls, got %d", len(st.Token))
+	}
+	merged := st.Token[1].(*url)
+	if merged.Loc != "https://www.douyacun.com/post?a=1&b=2" || merged.LastMod != first.LastMod ||
+		merged.ChangeFreq != Daily || merged.Priority != 0.8 {
+		t.Fatalf("unexpected merged url %+v", merged)
+	}
+	var images, videos int
+	for _, token := range merged.Token {
+		switch token.(type) {
+		case *image:
+			images++
+		case *video:
+			videos++
+		}
+	}
+	if images != 2 || videos != 2 {
+		t.Fatalf("expect 2 images and 2 videos, got %d, %d", images, videos)
+	}
+	// 已经生成的副本以及原来的网址不受影响
+	if len(first.Token) != 2 || first.Loc != "https://www.douyacun.com/post/?b=2&a=1" ||
+		len(snapshot.Token) != 3 || len(snapshot.Token[1].(*url).Token) != 2 {
+		t.Fatalf("merge modified the original url")
+	}
+	// SetCanonical 之前添加的网址也会规范化并合并
+	if before := st.Token[0].(*url); before.Loc != "https://www.douyacun.com/before" || before.LastMod != newer.Format(time.RFC3339) {
+		t.Fatalf("unexpected url %+v", before)
+	}
+
+	// 之后没有再添加网址时，输出时规范化
+	st = NewSiteMap()
+	st.SetFormat(TxtFormat)
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/a/"))
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/a?"))
+	st.SetCanonical(CanonicalAll)
+	data, err := st.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "https://www.douyacun.com/a\n" {
+		t.Fatalf("unexpected sitemap %q", data)
+	}
+}
+
+// 规范化不修改调用方的网址，拆分时也不会修改仍被sitemap引用的网址
+func TestSitemap_CanonicalConcurrent(t *testing.T) {
+	st := NewSiteMap()
+	st.SetMaxLinks(10)
+	st.SetCanonical(CanonicalAll)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if _, err := st.Split(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		u := NewUrl().SetLoc(fmt.Sprintf("https://WWW.douyacun.com/%d/#top", i%50))
+		st.AppendUrl(u)
+		if u.Loc != fmt.Sprintf("https://WWW.douyacun.com/%d/#top", i%50) {
+			t.Fatalf("caller's url modified: %s", u.Loc)
+		}
+	}
+	<-done
+	if len(st.Token) != 50 {
+		t.Fatalf("expect 50 urls, got %d", len(st.Token))
+	}
+}
+
+func TestStreamSiteMap_Canonical(t *testing.T) {
+	st := NewStreamSiteMap()
+	st.SetStorage(NewMemoryStorage())
+	st.SetCanonical(CanonicalAll)
+	u := NewUrl().SetLoc("https://WWW.douyacun.com/a/#top")
+	if err := st.AppendUrl(u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Loc != "https://WWW.douyacun.com/a/#top" {
+		t.Fatalf("caller's url modified: %s", u.Loc)
+	}
+	filename, err := st.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := readFile(st.store(), filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<loc>https://www.douyacun.com/a</loc>") {
+		t.Fatalf("unexpected sitemap %s", data)
+	}
+}
@@ -0,0 +1,384 @@
+// gositemap 从 CSV、JSON Lines 或纯文本（每行一个网址）生成sitemap
+//
+//	gositemap -host https://www.douyacun.com -public /tmp/sitemap -compress urls.csv
+//	cat urls.txt | gositemap -host https://www.douyacun.com -format text
+//	gositemap -validate /tmp/sitemap/sitemap.xml
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/douyacun/gositemap"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gositemap", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		host     = fs.String("host", "http://www.example.com", "默认域名，相对地址会以此补全，sitemapindex 也以此引用各个sitemap")
+		public   = fs.String("public", ".", "sitemap 文件的存放目录")
+		filename = fs.String("filename", "sitemap.xml", "sitemap 文件名，拆分后作为 sitemapindex 的文件名")
+		compress = fs.Bool("compress", false, "以 gzip 压缩sitemap文件")
+		pretty   = fs.Bool("pretty", false, "格式化输出的xml")
+		maxLinks = fs.Int("max-links", gositemap.MaxSitemapLinks, "单个sitemap文件最多的网址数量")
+		format   = fs.String("format", "", "输入格式 csv、jsonl、text，默认根据文件扩展名判断，标准输入默认为 text")
+		stream   = fs.Bool("stream", false, "流式写入，不在内存中保留网址")
+		validate = fs.Bool("validate", false, "校验参数中的sitemap文件，不生成sitemap")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *validate {
+		return validateFiles(fs.Args(), stdout, stderr)
+	}
+
+	var (
+		st      = gositemap.NewSiteMap()
+		sst     = gositemap.NewStreamSiteMap()
+		skipped int
+	)
+	for _, o := range []interface {
+		SetDefaultHost(string)
+		SetPublicPath(string)
+		SetFilename(string)
+		SetCompress(bool)
+		SetPretty(bool)
+		SetMaxLinks(int)
+	}{st, sst} {
+		o.SetDefaultHost(*host)
+		o.SetPublicPath(*public)
+		o.SetFilename(*filename)
+		o.SetCompress(*compress)
+		o.SetPretty(*pretty)
+		o.SetMaxLinks(*maxLinks)
+	}
+	// gositemap 的网址类型未导出，只能通过类型推导在这里构造
+	emit := func(r *record) error {
+		u := gositemap.NewUrl().SetLoc(r.Loc)
+		err := func() error {
+			if r.LastMod != "" {
+				lastMod, err := parseTime(r.LastMod)
+				if err != nil {
+					return fmt.Errorf("lastmod: %v", err)
+				}
+				u.SetLastmod(lastMod)
+			}
+			if r.ChangeFreq != "" {
+				u.SetChangefreq(gositemap.ChangeFreq(r.ChangeFreq))
+			}
+			if r.Priority != "" {
+				priority, err := r.Priority.Float64()
+				if err != nil {
+					return fmt.Errorf("priority: %v", err)
+				}
+				u.SetPriority(priority)
+			}
+			for _, i := range r.Images {
+				err := u.AppendImage(gositemap.NewImage().
+					SetLoc(i.Loc).
+					SetTitle(i.Title).
+					SetCaption(i.Caption).
+					SetGeoLocation(i.GeoLocation).
+					SetLicense(i.License))
+				if err != nil {
+					return err
+				}
+			}
+			for _, v := range r.Videos {
+				_video := gositemap.NewVideo().
+					SetThumbnailLoc(v.ThumbnailLoc).
+					SetTitle(v.Title).
+					SetDescription(v.Description).
+					SetContentLoc(v.ContentLoc)
+				if v.PlayerLoc != "" {
+					_video.SetPlayerLoc(v.PlayerLoc, true)
+				}
+				if v.Duration > 0 {
+					_video.SetDuration(time.Duration(v.Duration) * time.Second)
+				}
+				if v.PublicationDate != "" {
+					date, err := parseTime(v.PublicationDate)
+					if err != nil {
+						return fmt.Errorf("video:publication_date: %v", err)
+					}
+					_video.SetPublicationDate(date)
+				}
+				u.AppendVideo(_video)
+			}
+			if n := r.News; n != nil {
+				date, err := parseTime(n.PublicationDate)
+				if err != nil {
+					return fmt.Errorf("news:publication_date: %v", err)
+				}
+				u.AppendNews(gositemap.NewNews().
+					SetName(n.Name).
+					SetLanguage(n.Language).
+					SetTitle(n.Title).
+					SetPublicationDate(date))
+			}
+			return validateInput(u.Validate())
+		}()
+		if err != nil {
+			skipped++
+			fmt.Fprintf(stderr, "skip %s: %v\n", r.Loc, err)
+			return nil
+		}
+		if *stream {
+			return sst.AppendUrl(u)
+		}
+		st.AppendUrl(u)
+		return nil
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	for _, input := range inputs {
+		if err := readInput(input, *format, stdin, emit); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", input, err)
+			return 1
+		}
+	}
+
+	var (
+		out string
+		err error
+	)
+	if *stream {
+		out, err = sst.Close()
+	} else {
+		out, err = st.Storage()
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintln(stdout, path.Join(*public, out))
+	if skipped > 0 {
+		fmt.Fprintf(stderr, "%d invalid urls skipped\n", skipped)
+	}
+	return 0
+}
+
+func validateFiles(files []string, stdout, stderr io.Writer) int {
+	if len(files) == 0 {
+		fmt.Fprintln(stderr, "no sitemap files to validate")
+		return 2
+	}
+	code := 0
+	for _, file := range files {
+		err := gositemap.ValidateFile(file)
+		var errs gositemap.ValidationErrors
+		switch {
+		case err == nil:
+			fmt.Fprintf(stdout, "%s: ok\n", file)
+		case errors.As(err, &errs):
+			code = 1
+			for _, e := range errs {
+				fmt.Fprintf(stdout, "%s: %v\n", file, e)
+			}
+		default:
+			code = 1
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+		}
+	}
+	return code
+}
+
+// 读取一个输入，"-" 表示标准输入
+func readInput(input, format string, stdin io.Reader, emit func(*record) error) error {
+	r := stdin
+	if input != "-" {
+		fd, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		r = fd
+	}
+	if format == "" {
+		switch path.Ext(input) {
+		case ".csv":
+			format = "csv"
+		case ".json", ".jsonl", ".ndjson":
+			format = "jsonl"
+		default:
+			format = "text"
+		}
+	}
+	switch format {
+	case "csv":
+		return readCSV(r, emit)
+	case "jsonl", "json":
+		return readJSONLines(r, emit)
+	case "text", "txt":
+		return readText(r, emit)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// 每行一个网址，忽略空行和 # 开头的注释
+func readText(r io.Reader, emit func(*record) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := emit(&record{Loc: line}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// 每行一个 JSON 对象，字段见 record
+func readJSONLines(r io.Reader, emit func(*record) error) error {
+	d := json.NewDecoder(r)
+	for {
+		var rec record
+		if err := d.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := emit(&rec); err != nil {
+			return err
+		}
+	}
+}
+
+// 第一行为表头，支持的列：
+// loc, lastmod, changefreq, priority,
+// image_loc（多个图片以空格分隔）, image_title, image_caption,
+// video_thumbnail_loc, video_title, video_description, video_content_loc, video_player_loc,
+// news_name, news_language, news_title, news_publication_date
+func readCSV(r io.Reader, emit func(*record) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		col := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(row) {
+				col[strings.TrimSpace(name)] = strings.TrimSpace(row[i])
+			}
+		}
+		// priority 在 emit 中解析，错误的值与其他字段一样跳过该行
+		rec := &record{
+			Loc:        col["loc"],
+			LastMod:    col["lastmod"],
+			ChangeFreq: col["changefreq"],
+			Priority:   json.Number(col["priority"]),
+		}
+		for _, loc := range strings.Fields(col["image_loc"]) {
+			rec.Images = append(rec.Images, imageRecord{Loc: loc, Title: col["image_title"], Caption: col["image_caption"]})
+		}
+		if col["video_thumbnail_loc"] != "" || col["video_content_loc"] != "" || col["video_player_loc"] != "" {
+			rec.Videos = append(rec.Videos, videoRecord{
+				ThumbnailLoc: col["video_thumbnail_loc"],
+				Title:        col["video_title"],
+				Description:  col["video_description"],
+				ContentLoc:   col["video_content_loc"],
+				PlayerLoc:    col["video_player_loc"],
+			})
+		}
+		if col["news_name"] != "" || col["news_title"] != "" {
+			rec.News = &newsRecord{
+				Name:            col["news_name"],
+				Language:        col["news_language"],
+				Title:           col["news_title"],
+				PublicationDate: col["news_publication_date"],
+			}
+		}
+		if err := emit(rec); err != nil {
+			return err
+		}
+	}
+}
+
+type record struct {
+	Loc        string        `json:"loc"`
+	LastMod    string        `json:"lastmod"`
+	ChangeFreq string        `json:"changefreq"`
+	Priority   json.Number   `json:"priority"`
+	Images     []imageRecord `json:"images"`
+	Videos     []videoRecord `json:"videos"`
+	News       *newsRecord   `json:"news"`
+}
+
+type imageRecord struct {
+	Loc         string `json:"loc"`
+	Title       string `json:"title"`
+	Caption     string `json:"caption"`
+	GeoLocation string `json:"geo_location"`
+	License     string `json:"license"`
+}
+
+type videoRecord struct {
+	ThumbnailLoc    string `json:"thumbnail_loc"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	ContentLoc      string `json:"content_loc"`
+	PlayerLoc       string `json:"player_loc"`
+	Duration        int    `json:"duration"`
+	PublicationDate string `json:"publication_date"`
+}
+
+type newsRecord struct {
+	Name            string `json:"name"`
+	Language        string `json:"language"`
+	Title           string `json:"title"`
+	PublicationDate string `json:"publication_date"`
+}
+
+func parseTime(s string) (t time.Time, err error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err = time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	return
+}
+
+// 输入中的网址校验结果，新闻的发布时间不作为错误，归档的新闻数据同样可以生成sitemap
+func validateInput(err error) error {
+	var errs gositemap.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	var kept gositemap.ValidationErrors
+	for _, e := range errs {
+		if !errors.Is(e, gositemap.NewsExpiredError) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
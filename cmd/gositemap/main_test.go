@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	csvInput := `loc,lastmod,changefreq,priority,image_loc,image_title
+/article1.html,2020-04-19,daily,0.8,https://www.douyacun.com/1.jpg https://www.douyacun.com/2.jpg,example
+/article2.html,,weekly,2,,
+/article6.html,,daily,high,,
+`
+	jsonInput := `{"loc": "/article3.html", "news": {"name": "《示例时报》", "language": "zh-cn", "title": "title", "publication_date": "` + time.Now().Format(time.RFC3339) + `"}}
+{"loc": "/article4.html", "videos": [{"thumbnail_loc": "https://www.douyacun.com/1.jpg", "title": "title", "description": "description", "content_loc": "https://www.douyacun.com/1.mp4", "duration": 600}]}
+`
+	csvFile := path.Join(dir, "urls.csv")
+	jsonFile := path.Join(dir, "urls.jsonl")
+	ioutil.WriteFile(csvFile, []byte(csvInput), 0666)
+	ioutil.WriteFile(jsonFile, []byte(jsonInput), 0666)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-host", "https://www.douyacun.com", "-public", dir, "-max-links", "2", csvFile, jsonFile, "-"}
+	if code := run(args, strings.NewReader("/article5.html\n# comment\n"), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "skip /article2.html") || strings.Contains(stderr.String(), "article3") {
+		t.Fatalf("expected invalid priority to be skipped: %s", stderr.String())
+	}
+	// 无法解析的 priority 同样跳过该行，不中断整个输入
+	if !strings.Contains(stderr.String(), "skip /article6.html: priority:") || !strings.Contains(stderr.String(), "2 invalid urls skipped") {
+		t.Fatalf("expected malformed priority to be skipped: %s", stderr.String())
+	}
+	if strings.TrimSpace(stdout.String()) != path.Join(dir, "sitemap.xml") {
+		t.Fatalf("unexpected output %s", stdout.String())
+	}
+	for _, name := range []string{"sitemap-1.xml", "sitemap-2.xml"} {
+		data, err := ioutil.ReadFile(path.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "sitemap-1.xml" && !strings.Contains(string(data), "<image:loc>https://www.douyacun.com/2.jpg</image:loc>") {
+			t.Fatalf("missing image in %s:\n%s", name, data)
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-validate", path.Join(dir, "sitemap-1.xml")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stdout.String())
+	}
+}
+
+func TestRun_ArchivedNews(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 超过 48 小时的新闻不会被跳过，缺少必填字段的仍然跳过
+	input := `{"loc": "/archived.html", "news": {"name": "《示例时报》", "language": "zh-cn", "title": "title", "publication_date": "2019-04-19T08:00:00Z"}}
+{"loc": "/invalid.html", "news": {"name": "《示例时报》", "language": "zh-cn", "publication_date": "2019-04-19T08:00:00Z"}}
+`
+	var stdout, stderr bytes.Buffer
+	args := []string{"-host", "https://www.douyacun.com", "-public", dir, "-format", "jsonl", "-"}
+	if code := run(args, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if strings.Contains(stderr.String(), "archived") || !strings.Contains(stderr.String(), "skip /invalid.html") {
+		t.Fatalf("unexpected skips: %s", stderr.String())
+	}
+	data, err := ioutil.ReadFile(path.Join(dir, "sitemap.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "/archived.html") {
+		t.Fatalf("archived news missing:\n%s", data)
+	}
+}
@@ -0,0 +1,296 @@
+package gositemap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCrawlDepth defines default depth of links to follow from defaultHost
+	DefaultCrawlDepth = 3
+	// DefaultCrawlConcurrency defines default number of pages fetched at the same time
+	DefaultCrawlConcurrency = 4
+)
+
+// 从 defaultHost 开始抓取同一站点的网页，将发现的网页添加到sitemap
+// 遵守 robots.txt、<meta name="robots"> 和 canonical，网页中的 <img>、<video> 会作为图片和视频添加
+type crawler struct {
+	st          *sitemap
+	client      *http.Client
+	userAgent   string
+	depth       int
+	concurrency int
+	include     []*regexp.Regexp
+	exclude     []*regexp.Regexp
+}
+
+func NewCrawler(st *sitemap) *crawler {
+	return &crawler{
+		st:          st,
+		client:      http.DefaultClient,
+		userAgent:   "gositemap",
+		depth:       DefaultCrawlDepth,
+		concurrency: DefaultCrawlConcurrency,
+	}
+}
+
+func (c *crawler) SetClient(client *http.Client) *crawler {
+	c.client = client
+	return c
+}
+
+// 请求时使用的 User-agent，同时用于匹配 robots.txt 中的分组
+func (c *crawler) SetUserAgent(userAgent string) *crawler {
+	c.userAgent = userAgent
+	return c
+}
+
+// 从首页开始最多跟随几层链接
+func (c *crawler) SetDepth(depth int) *crawler {
+	if depth >= 0 {
+		c.depth = depth
+	}
+	return c
+}
+
+// 同时抓取的网页数量
+func (c *crawler) SetConcurrency(concurrency int) *crawler {
+	if concurrency > 0 {
+		c.concurrency = concurrency
+	}
+	return c
+}
+
+// 只抓取匹配其中任意一个规则的网址
+func (c *crawler) SetInclude(patterns ...*regexp.Regexp) *crawler {
+	c.include = patterns
+	return c
+}
+
+// 不抓取匹配其中任意一个规则的网址
+func (c *crawler) SetExclude(patterns ...*regexp.Regexp) *crawler {
+	c.exclude = patterns
+	return c
+}
+
+// 抓取的结果
+type crawled struct {
+	// 跳转之后的网址
+	loc     string
+	lastMod time.Time
+	page    *page
+	err     error
+}
+
+// 开始抓取，按照发现的顺序添加到sitemap
+func (c *crawler) Crawl(ctx context.Context) error {
+	start, err := neturl.Parse(strings.TrimRight(c.st.defaultHost, "/") + "/")
+	if err != nil {
+		return err
+	}
+	rules := c.robots(ctx, start)
+	if !rules.allowed(start.RequestURI()) {
+		return nil
+	}
+	client := c.redirectClient(start, rules)
+	visited := map[string]bool{start.String(): true}
+	level := []string{start.String()}
+	for depth := 0; len(level) > 0; depth++ {
+		results := c.fetchAll(ctx, client, level)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var next []string
+		for i, r := range results {
+			if r.err != nil || r.page == nil {
+				continue
+			}
+			// 跳转到已经抓取过的网址时丢弃，其他站点和 robots.txt 禁止的网址不会跟随跳转，见 redirectClient
+			if r.loc != level[i] {
+				if visited[r.loc] {
+					continue
+				}
+				visited[r.loc] = true
+			}
+			follow := func(loc string) {
+				if visited[loc] || !c.sameHost(start, loc) || !c.match(loc) {
+					return
+				}
+				if u, err := neturl.Parse(loc); err != nil || !rules.allowed(u.RequestURI()) {
+					return
+				}
+				visited[loc] = true
+				next = append(next, loc)
+			}
+			if r.page.canonical != "" && r.page.canonical != r.loc {
+				follow(r.page.canonical)
+				continue
+			}
+			if !r.page.noindex && c.match(r.loc) {
+				c.st.AppendUrl(c.url(r))
+			}
+			if !r.page.nofollow && depth < c.depth {
+				for _, link := range r.page.links {
+					follow(link)
+				}
+			}
+		}
+		level = next
+	}
+	return nil
+}
+
+// 跳转到其他站点或者 robots.txt 禁止的网址时不发送请求，直接返回跳转的响应
+// 其余的跳转交给 SetClient 设置的 CheckRedirect，没有设置时与 http.Client 相同，最多跳转 10 次
+func (c *crawler) redirectClient(start *neturl.URL, rules *robots) *http.Client {
+	client := *c.client
+	check := c.client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !c.sameHost(start, req.URL.String()) || !rules.allowed(req.URL.RequestURI()) {
+			return http.ErrUseLastResponse
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &client
+}
+
+// 并发抓取同一层的网页，结果与 locs 的顺序一致
+func (c *crawler) fetchAll(ctx context.Context, client *http.Client, locs []string) []crawled {
+	var (
+		wg      sync.WaitGroup
+		jobs    = make(chan int)
+		results = make([]crawled, len(locs))
+	)
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.fetch(ctx, client, locs[i])
+			}
+		}()
+	}
+	for i := range locs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func (c *crawler) fetch(ctx context.Context, client *http.Client, loc string) crawled {
+	result := crawled{loc: loc}
+	req, err := http.NewRequest(http.MethodGet, loc, nil)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	defer resp.Body.Close()
+	// 跟随跳转之后实际的网址
+	final := *resp.Request.URL
+	final.Fragment = ""
+	result.loc = final.String()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return result
+	}
+	if robots := strings.ToLower(resp.Header.Get("X-Robots-Tag")); strings.Contains(robots, "noindex") || strings.Contains(robots, "none") {
+		return result
+	}
+	if lastMod, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		result.lastMod = lastMod
+	}
+	result.page, result.err = parseHTML(resp.Body, resp.Request.URL)
+	return result
+}
+
+// 获取 robots.txt，不存在时允许抓取所有网页
+func (c *crawler) robots(ctx context.Context, start *neturl.URL) *robots {
+	u := *start
+	u.Path, u.RawQuery = "/robots.txt", ""
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return &robots{}
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &robots{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &robots{}
+	}
+	return parseRobots(resp.Body, c.userAgent)
+}
+
+// 网页转换成网址，视频缺少标题、说明时使用网页的标题、说明
+func (c *crawler) url(r crawled) *url {
+	u := NewUrl().SetLoc(r.loc)
+	if !r.lastMod.IsZero() {
+		u.SetLastmod(r.lastMod)
+	}
+	// 超过 1000 张时只保留前 1000 张
+	for _, i := range r.page.images {
+		if err := u.AppendImage(i); err != nil {
+			break
+		}
+	}
+	for _, v := range r.page.videos {
+		if v.ThumbnailLoc == "" || v.ContentLoc == "" {
+			continue
+		}
+		if v.Title == "" {
+			v.SetTitle(r.page.title)
+		}
+		if v.Description == "" {
+			v.SetDescription(r.page.description)
+		}
+		if v.Description == "" {
+			v.SetDescription(v.Title)
+		}
+		u.AppendVideo(v)
+	}
+	return u
+}
+
+func (c *crawler) sameHost(start *neturl.URL, loc string) bool {
+	u, err := neturl.Parse(loc)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, start.Host)
+}
+
+func (c *crawler) match(loc string) bool {
+	for _, p := range c.exclude {
+		if p.MatchString(loc) {
+			return false
+		}
+	}
+	if len(c.include) == 0 {
+		return true
+	}
+	for _, p := range c.include {
+		if p.MatchString(loc) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package gositemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCrawler_Crawl(t *testing.T) {
+	pages := map[string]string{
+		"/": `<html><head><title>首页</title></head><body>
+			<a href="/a">a</a> <a href="b#top">b</a> <a href="/private/x">private</a>
+			<a href="https://www.example.com/">external</a> <a href="/noindex">noindex</a>
+			<a href="/dup">dup</a> <a href="/nofollow">nofollow</a> <a href="/tag/go">tag</a>
+			<script>if (a < b && c > d) { document.write("<a href='/script'>") }</script>
+		</body></html>`,
+		"/a": `<html><head><title>A</title><meta name="description" content="页面A"></head><body>
+			<img src="/images/a.jpg" alt="图片A">
+			<video poster="/images/v.jpg" title="视频"><source src="/videos/v.mp4"></video>
+			<a href="/">home</a>
+		</body></html>`,
+		"/b":         `<html><body><p>b<br>c</p><a href="/c">c</a></body></html>`,
+		"/c":         `<html><body>c</body></html>`,
+		"/noindex":   `<html><head><meta name="robots" content="noindex"></head><body><a href="/d">d</a></body></html>`,
+		"/d":         `<html><body>d</body></html>`,
+		"/dup":       `<html><head><link rel="canonical" href="/a"></head><body></body></html>`,
+		"/nofollow":  `<html><head><meta name="robots" content="nofollow"></head><body><a href="/e">e</a></body></html>`,
+		"/e":         `<html><body>e</body></html>`,
+		"/private/x": `<html><body>private</body></html>`,
+		"/tag/go":    `<html><body>tag</body></html>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Last-Modified", "Sun, 19 Apr 2020 09:28:33 GMT")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	st := NewSiteMap()
+	st.SetDefaultHost(server.URL)
+	err := NewCrawler(st).
+		SetConcurrency(3).
+		SetExclude(regexp.MustCompile(`/tag/`)).
+		Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var locs []string
+	for _, token := range st.Token {
+		locs = append(locs, strings.TrimPrefix(token.(*url).Loc, server.URL))
+	}
+	want := []string{"/", "/a", "/b", "/nofollow", "/c", "/d"}
+	if strings.Join(locs, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, locs)
+	}
+
+	a := st.Token[1].(*url)
+	if a.LastMod != "2020-04-19T09:28:33Z" || len(a.Token) != 2 {
+		t.Fatalf("unexpected url %+v", a)
+	}
+	if i := a.Token[0].(*image); i.Loc != server.URL+"/images/a.jpg" || i.Caption != "图片A" {
+		t.Fatalf("unexpected image %+v", i)
+	}
+	if v := a.Token[1].(*video); v.ContentLoc != server.URL+"/videos/v.mp4" || v.Title != "视频" || v.Description != "页面A" {
+		t.Fatalf("unexpected video %+v", v)
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCrawler_Redirect(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		blocked []string
+	)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		blocked = append(blocked, "external")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body>external</body></html>`)
+	}))
+	defer external.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/again":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, external.URL+"/", http.StatusFound)
+		case "/secret":
+			http.Redirect(w, r, "/private", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/private":
+			mu.Lock()
+			blocked = append(blocked, "private")
+			mu.Unlock()
+			fallthrough
+		case "/", "/a", "/new":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><body><a href="/old">old</a> <a href="/a">a</a> <a href="/again">again</a> <a href="/away">away</a> <a href="/secret">secret</a> <a href="/loop">loop</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var redirects int
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		redirects++
+		if len(via) >= 3 {
+			return errors.New("too many redirects")
+		}
+		return nil
+	}}
+	st := NewSiteMap()
+	st.SetDefaultHost(server.URL)
+	if err := NewCrawler(st).SetClient(client).SetConcurrency(1).Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var locs []string
+	for _, token := range st.Token {
+		locs = append(locs, strings.TrimPrefix(token.(*url).Loc, server.URL))
+	}
+	// 记录跳转之后的网址，跳转到其他站点的网页被丢弃
+	if got := strings.Join(locs, " "); got != "/ /new /a" {
+		t.Fatalf("unexpected urls %s", got)
+	}
+	// 其他站点和 robots.txt 禁止的网址不会被请求
+	if len(blocked) != 0 {
+		t.Fatalf("redirect target fetched %v", blocked)
+	}
+	// SetClient 中的 CheckRedirect 仍然生效：/old、/again 各一次，/loop 三次后停止
+	if redirects != 5 {
+		t.Fatalf("expect 5 redirects checked, got %d", redirects)
+	}
+}
+
+func TestCrawler_DisallowStart(t *testing.T) {
+	var fetched bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /\n")
+			return
+		}
+		fetched = true
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body>home</body></html>`)
+	}))
+	defer server.Close()
+
+	st := NewSiteMap()
+	st.SetDefaultHost(server.URL)
+	if err := NewCrawler(st).Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fetched || len(st.Token) != 0 {
+		t.Fatalf("start url disallowed by robots.txt was crawled")
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	txt := `
+User-agent: *
+Disallow: /
+
+User-agent: gositemap
+User-agent: other
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+`
+	rules := parseRobots(strings.NewReader(txt), "gositemap/1.0")
+	for path, allowed := range map[string]bool{
+		"/":                    true,
+		"/private/x":           false,
+		"/private/public/page": true,
+		"/files/a.pdf":         false,
+		"/files/a.pdf?x=1":     true,
+	} {
+		if rules.allowed(path) != allowed {
+			t.Errorf("%s: expected allowed=%v", path, allowed)
+		}
+	}
+	if parseRobots(strings.NewReader(txt), "bot").allowed("/") {
+		t.Errorf("expected wildcard group to disallow /")
+	}
+}
@@ -0,0 +1,125 @@
+package gositemap
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+// urlset 流式编码器，每写入一个网址立即输出一个 <url>，不在内存中保留网址
+// 流式写入无法预先知道用到了哪些扩展，所以 urlset 上会声明全部扩展的命名空间
+type encoder struct {
+	*options
+	w       *countWriter
+	enc     *xml.Encoder
+	links   int
+	lastMod time.Time
+	started bool
+}
+
+func NewEncoder(w io.Writer, options *options) *encoder {
+	cw := &countWriter{w: w}
+	return &encoder{
+		options: options,
+		w:       cw,
+		enc:     xml.NewEncoder(cw),
+	}
+}
+
+// 写入一个网址，超过 maxLinks 或 maxBytes 时返回 TooMuchLinksError/TooLargeError，此时不会写入任何内容
+func (e *encoder) Encode(u *url) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if e.links >= e.maxLinks {
+		return TooMuchLinksError
+	}
+	data, err := marshalToken(u, e.pretty)
+	if err != nil {
+		return err
+	}
+	if e.w.n+len(data)+e.closingSize() > e.maxBytes {
+		return TooLargeError
+	}
+	if _, err = e.w.Write(data); err != nil {
+		return err
+	}
+	e.links++
+	if t, err := parseW3CDate(u.LastMod); err == nil && t.After(e.lastMod) {
+		e.lastMod = t
+	}
+	return nil
+}
+
+// 写入 </urlset>，不会关闭底层的 io.Writer
+func (e *encoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if e.pretty && e.links > 0 {
+		if _, err := e.w.Write([]byte("\n")); err != nil {
+			return err
+		}
+	}
+	if err := e.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "urlset"}}); err != nil {
+		return err
+	}
+	return e.enc.Flush()
+}
+
+// 已写入的网址数量
+func (e *encoder) Links() int {
+	return e.links
+}
+
+// 已写入网址中最新的 lastmod，没有时为零值
+func (e *encoder) LastMod() time.Time {
+	return e.lastMod
+}
+
+// 已写入的字节数（未压缩）
+func (e *encoder) Size() int {
+	return e.w.n
+}
+
+func (e *encoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	header := xml.Header
+	if !e.pretty {
+		header = strings.Trim(xml.Header, "\n")
+	}
+	if _, err := e.w.Write([]byte(header)); err != nil {
+		return err
+	}
+	// 声明全部已注册的命名空间，前缀重复时使用先注册的
+	attrs, _ := namespaceAttrs(e.profile.namespaces(^xmlns(0)), false)
+	start := xml.StartElement{Name: xml.Name{Local: "urlset"}, Attr: attrs}
+	if err := e.enc.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.enc.Flush()
+}
+
+func (e *encoder) closingSize() int {
+	n := len("</urlset>")
+	if e.pretty {
+		n++
+	}
+	return n
+}
+
+// 记录写入的字节数
+type countWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
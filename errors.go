@@ -0,0 +1,61 @@
+package gositemap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 字段校验错误
+type FieldError struct {
+	Loc   string      // 所属网页的网址
+	Field string      // 字段名称，与xml标签一致，如 priority、video:duration
+	Value interface{} // 字段的值
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Loc == "" {
+		return fmt.Sprintf("%s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Loc, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// 多个字段的校验错误
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msg := make([]string, 0, len(e))
+	for _, err := range e {
+		msg = append(msg, err.Error())
+	}
+	return strings.Join(msg, "; ")
+}
+
+// 返回 error，没有错误时为 nil
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// 设置字段时收集校验错误，不再 panic，错误的值不会被设置
+type validation struct {
+	errs ValidationErrors
+}
+
+func (v *validation) addError(field string, value interface{}, err error) {
+	v.errs = append(v.errs, &FieldError{
+		Field: field,
+		Value: value,
+		Err:   err,
+	})
+}
+
+func (v *validation) validate() ValidationErrors {
+	return v.errs
+}
@@ -0,0 +1,52 @@
+package gositemap
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUrl_Validate(t *testing.T) {
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+
+	valid := NewUrl().SetLoc("https://www.douyacun.com/valid.html").SetPriority(0.5)
+	valid.AppendNews(NewNews().SetName("《示例时报》").SetLanguage("en").SetTitle("title").SetPublicationDate(time.Now()))
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	st.AppendUrl(valid)
+
+	invalid := NewUrl().SetLoc("https://www.douyacun.com/invalid.html").SetPriority(2)
+	invalid.AppendVideo(NewVideo().
+		SetThumbnailLoc("https://www.douyacun.com/thumbs/123.jpg").
+		SetTitle("title").
+		SetDescription("description").
+		SetContentLoc("https://www.douyacun.com/video123.mp4").
+		SetDuration(10 * time.Hour).
+		SetRating(4).
+		SetTag(make([]string, 33)))
+	err := invalid.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected 3 field errors, got %v", err)
+	}
+	if errs[0].Field != "priority" || errs[0].Loc != invalid.Loc {
+		t.Fatalf("unexpected error %+v", errs[0])
+	}
+	var priorityErr *InvalidPriorityError
+	if !errors.As(errs[0], &priorityErr) {
+		t.Fatalf("expected InvalidPriorityError, got %v", errs[0].Err)
+	}
+	if !errors.Is(errs[1], InvalidDurationError) || !errors.Is(errs[2], InvalidTagError) {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if invalid.Priority != 0 {
+		t.Fatalf("invalid priority should not be set")
+	}
+	st.AppendUrl(invalid)
+
+	if err = st.Validate(); !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected 3 field errors, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package gositemap
+
+import (
+	"encoding/xml"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	InvalidPrefixError     = errors.New("命名空间前缀只能包含字母、数字、-、_ 和 .，且不能以数字开头")
+	TooManyNamespacesError = errors.New("最多注册 64 个命名空间")
+	PrefixConflictError    = errors.New("同一个urlset中使用了前缀相同的命名空间")
+)
+
+var prefixRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// 已注册的命名空间，按照注册的顺序在 urlset 上声明
+type namespace struct {
+	bit    xmlns
+	prefix string
+	uri    string
+}
+
+var (
+	namespacesMu sync.RWMutex
+	namespaces   = []namespace{
+		{VideoXmlNS, "video", VideoNamespace},
+		{ImageXmlNS, "image", ImageNamespace},
+		{NewsXmlNS, "news", NewsNamespace},
+		{XhtmlXmlNS, "xhtml", XhtmlNamespace},
+		{MobileXmlNS, "mobile", MobileNamespace},
+		{BaiduMobileXmlNS, "mobile", BaiduMobileNamespace},
+	}
+)
+
+// 注册自定义扩展的命名空间，返回的 xmlns 用于 url.AppendExtension
+// 同一个 uri 重复注册时返回之前的 xmlns；前缀可以与其他命名空间相同，但不能在同一个urlset中同时使用
+func RegisterNamespace(prefix, uri string) (xmlns, error) {
+	if !prefixRegexp.MatchString(prefix) || strings.HasPrefix(strings.ToLower(prefix), "xml") {
+		return 0, InvalidPrefixError
+	}
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	for _, ns := range namespaces {
+		if ns.uri == uri {
+			return ns.bit, nil
+		}
+	}
+	if len(namespaces) >= 64 {
+		return 0, TooManyNamespacesError
+	}
+	bit := xmlns(1) << uint(len(namespaces))
+	namespaces = append(namespaces, namespace{bit, prefix, uri})
+	return bit, nil
+}
+
+// 根据 uri 查找已注册的命名空间
+func lookupNamespace(uri string) (namespace, bool) {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+	for _, ns := range namespaces {
+		if ns.uri == uri {
+			return ns, true
+		}
+	}
+	return namespace{}, false
+}
+
+// urlset 上的命名空间声明，只声明 used 中用到的命名空间
+// strict 为 false 时跳过前缀重复的命名空间，用于无法预知会用到哪些扩展的流式写入
+func namespaceAttrs(used xmlns, strict bool) ([]xml.Attr, error) {
+	namespacesMu.RLock()
+	defer namespacesMu.RUnlock()
+	attrs := []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: SitemapNamespace}}
+	prefixes := make(map[string]bool)
+	for _, ns := range namespaces {
+		if used&ns.bit == 0 {
+			continue
+		}
+		if prefixes[ns.prefix] {
+			if strict {
+				return nil, PrefixConflictError
+			}
+			continue
+		}
+		prefixes[ns.prefix] = true
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + ns.prefix}, Value: ns.uri})
+	}
+	return attrs, nil
+}
+
+// 声明用到的命名空间
+func (s *urlSet) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return s.marshal(e, s.xmlns)
+}
+
+// 按照输出规范声明命名空间
+func (s *sitemap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return s.urlSet.marshal(e, s.profile.namespaces(s.xmlns))
+}
+
+func (s *urlSet) marshal(e *xml.Encoder, used xmlns) error {
+	attrs, err := namespaceAttrs(used, true)
+	if err != nil {
+		return err
+	}
+	start := xml.StartElement{Name: xml.Name{Local: "urlset"}, Attr: attrs}
+	if err = e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, token := range s.Token {
+		if err = e.Encode(token); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// 添加自定义扩展的元素，ns 为 RegisterNamespace 返回的值
+// element 序列化时需要使用带前缀的名称，如 `xml:"pagemap:PageMap"`
+func (u *url) AppendExtension(ns xmlns, element interface{}) {
+	u.setNs(ns)
+	u.Token = append(u.Token, element)
+}
+
+// 解析时遇到的已注册命名空间中的元素，按照原样保留，输出时使用注册的前缀
+type extensionElement struct {
+	tokens []xml.Token
+}
+
+// 读取 start 及其子节点，元素和属性的名称替换为 前缀:名称
+func decodeExtension(d *xml.Decoder, start xml.StartElement) (*extensionElement, error) {
+	e := &extensionElement{}
+	token := xml.Token(start)
+	for depth := 0; ; {
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			el := xml.StartElement{Name: prefixedName(t.Name)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				el.Attr = append(el.Attr, xml.Attr{Name: prefixedName(attr.Name), Value: attr.Value})
+			}
+			e.tokens = append(e.tokens, el)
+		case xml.EndElement:
+			depth--
+			e.tokens = append(e.tokens, xml.EndElement{Name: prefixedName(t.Name)})
+			if depth == 0 {
+				return e, nil
+			}
+		case xml.CharData:
+			e.tokens = append(e.tokens, t.Copy())
+		}
+		var err error
+		if token, err = d.Token(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// 已注册的命名空间使用注册的前缀，其他命名空间只保留名称
+func prefixedName(name xml.Name) xml.Name {
+	if ns, ok := lookupNamespace(name.Space); ok {
+		return xml.Name{Local: ns.prefix + ":" + name.Local}
+	}
+	return xml.Name{Local: name.Local}
+}
+
+func (e *extensionElement) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	for _, token := range e.tokens {
+		if err := enc.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package gositemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+type pageMap struct {
+	XMLName    xml.Name `xml:"pagemap:PageMap"`
+	DataObject struct {
+		Type      string `xml:"type,attr"`
+		Attribute []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"pagemap:Attribute"`
+	} `xml:"pagemap:DataObject"`
+}
+
+func TestRegisterNamespace(t *testing.T) {
+	ns, err := RegisterNamespace("pagemap", "http://www.google.com/schemas/sitemap-pagemap/1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := RegisterNamespace("pagemap", "http://www.google.com/schemas/sitemap-pagemap/1.0"); again != ns {
+		t.Fatalf("expected same xmlns for same uri")
+	}
+	if _, err = RegisterNamespace("1st", "http://www.example.com/1st"); err != InvalidPrefixError {
+		t.Fatalf("expected InvalidPrefixError, got %v", err)
+	}
+
+	p := &pageMap{}
+	p.DataObject.Type = "document"
+	p.DataObject.Attribute = append(p.DataObject.Attribute, struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	}{"title", "Coffee & tea"})
+	u := NewUrl().SetLoc("https://www.douyacun.com/")
+	u.AppendExtension(ns, p)
+	st := NewSiteMap()
+	st.AppendUrl(u)
+	data, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:pagemap="http://www.google.com/schemas/sitemap-pagemap/1.0">` +
+		`<url><loc>https://www.douyacun.com/</loc><pagemap:PageMap><pagemap:DataObject type="document">` +
+		`<pagemap:Attribute name="title">Coffee &amp; tea</pagemap:Attribute></pagemap:DataObject></pagemap:PageMap></url></urlset>`
+	if !strings.HasSuffix(string(data), want) {
+		t.Fatalf("unexpected xml\n%s", data)
+	}
+
+	// 解析时保留已注册命名空间中的元素，文档中使用其他前缀时输出注册的前缀
+	doc := strings.Replace(strings.Replace(string(data), "pagemap:", "pm:", -1), "xmlns:pagemap", "xmlns:pm", 1)
+	parsed, err := ParseSiteMap(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := parsed.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatalf("unexpected round trip\n%s\n%s", out, data)
+	}
+}
+
+func TestUrlSet_PrefixConflict(t *testing.T) {
+	ns, err := RegisterNamespace("mobile", "http://www.example.com/schemas/sitemap-mobile/1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := NewSiteMap()
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/").SetMobile(true))
+	u := NewUrl().SetLoc("https://www.douyacun.com/a")
+	u.AppendExtension(ns, &struct {
+		XMLName xml.Name `xml:"mobile:mobile"`
+		Type    string   `xml:"type,attr"`
+	}{Type: "mobile"})
+	st.AppendUrl(u)
+	if _, err = st.ToXml(); err != PrefixConflictError {
+		t.Fatalf("expected PrefixConflictError, got %v", err)
+	}
+}
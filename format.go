@@ -0,0 +1,238 @@
+package gositemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	StreamFormatError = errors.New("流式生成只支持 xml 格式")
+)
+
+// sitemap 文件格式，sitemaps.org 协议同时支持纯文本和 RSS 2.0、Atom 1.0 订阅源
+type Format int
+
+const (
+	// <urlset>，默认格式
+	XmlFormat Format = iota
+	// 每行一个网址的 UTF-8 文本
+	TxtFormat
+	// RSS 2.0，网址作为 <item>
+	RssFormat
+	// Atom 1.0，网址作为 <entry>
+	AtomFormat
+)
+
+// 文件扩展名
+func (f Format) ext() string {
+	switch f {
+	case TxtFormat:
+		return ".txt"
+	case RssFormat:
+		return ".rss"
+	case AtomFormat:
+		return ".atom"
+	}
+	return ".xml"
+}
+
+func (f Format) contentType() string {
+	switch f {
+	case TxtFormat:
+		return "text/plain; charset=utf-8"
+	case RssFormat:
+		return "application/rss+xml; charset=utf-8"
+	case AtomFormat:
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/xml; charset=utf-8"
+}
+
+// 按照 format 输出，见 ToXml、ToTxt、ToRss、ToAtom
+func (s *sitemap) Render() ([]byte, error) {
+	switch s.format {
+	case TxtFormat:
+		return s.ToTxt()
+	case RssFormat:
+		return s.ToRss()
+	case AtomFormat:
+		return s.ToAtom()
+	}
+	return s.ToXml()
+}
+
+// 纯文本格式，每行一个网址，与 ToXml 一样受 maxLinks 和 maxBytes 限制
+func (s *sitemap) ToTxt() ([]byte, error) {
+	s = s.snapshot()
+	if len(s.urlSet.Token) > s.options.maxLinks {
+		return nil, TooMuchLinksError
+	}
+	var buf bytes.Buffer
+	for _, token := range s.Token {
+		buf.WriteString(token.(*url).Loc)
+		buf.WriteByte('\n')
+	}
+	if buf.Len() > s.options.maxBytes {
+		return nil, TooLargeError
+	}
+	return buf.Bytes(), nil
+}
+
+// RSS 2.0 订阅源，lastmod 作为 <pubDate>
+func (s *sitemap) ToRss() ([]byte, error) {
+	s = s.snapshot()
+	feed := &rssFeed{Version: "2.0"}
+	feed.Channel.Title = s.feedTitle()
+	feed.Channel.Link = s.defaultHost
+	feed.Channel.Description = s.feedTitle()
+	for _, token := range s.Token {
+		feed.Channel.Items = append(feed.Channel.Items, newRssItem(token.(*url)))
+	}
+	return s.marshalFeed(feed)
+}
+
+// Atom 1.0 订阅源，lastmod 作为 <updated>
+// 网址都没有 lastmod 时使用生成的时间
+func (s *sitemap) ToAtom() ([]byte, error) {
+	s = s.snapshot()
+	updated := s.lastMod()
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed := &atomFeed{
+		Xmlns:   AtomNamespace,
+		Id:      s.absUrl(s.formatFilename()),
+		Title:   s.feedTitle(),
+		Updated: updated.UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: s.defaultHost},
+	}
+	feed.Author.Name = s.feedTitle()
+	for _, token := range s.Token {
+		feed.Entries = append(feed.Entries, newAtomEntry(token.(*url), feed.Updated))
+	}
+	return s.marshalFeed(feed)
+}
+
+func (s *sitemap) marshalFeed(feed interface{}) ([]byte, error) {
+	if len(s.urlSet.Token) > s.options.maxLinks {
+		return nil, TooMuchLinksError
+	}
+	var (
+		data []byte
+		err  error
+		buf  bytes.Buffer
+	)
+	if s.options.pretty {
+		buf.Write([]byte(xml.Header))
+		data, err = xml.MarshalIndent(feed, "", "  ")
+	} else {
+		buf.Write([]byte(strings.Trim(xml.Header, "\n")))
+		data, err = xml.Marshal(feed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(data)
+	if buf.Len() > s.options.maxBytes {
+		return nil, TooLargeError
+	}
+	return buf.Bytes(), nil
+}
+
+// 订阅源的标题，默认为 defaultHost
+func (s *sitemap) feedTitle() string {
+	if s.title != "" {
+		return s.title
+	}
+	return s.defaultHost
+}
+
+// 单个网址按照 format 输出的内容，用于拆分时计算文件大小
+func (s *sitemap) marshalUrl(u *url) ([]byte, error) {
+	switch s.format {
+	case TxtFormat:
+		return []byte(u.Loc + "\n"), nil
+	case RssFormat:
+		// <rss><channel><item>
+		return marshalIndent(newRssItem(u), "    ", s.pretty)
+	case AtomFormat:
+		// 没有 lastmod 的网址使用订阅源的 updated，长度与 RFC3339 的 UTC 时间相同
+		return marshalIndent(newAtomEntry(u, time.Time{}.Format(time.RFC3339)), "  ", s.pretty)
+	}
+	return marshalToken(u, s.pretty)
+}
+
+// pretty时包含前面的换行和缩进
+func marshalIndent(v interface{}, prefix string, pretty bool) ([]byte, error) {
+	if pretty {
+		data, err := xml.MarshalIndent(v, prefix, "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte("\n"), data...), nil
+	}
+	return xml.Marshal(v)
+}
+
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title       string     `xml:"title"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Items       []*rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	XMLName xml.Name `xml:"item"`
+	Link    string   `xml:"link"`
+	Guid    string   `xml:"guid"`
+	PubDate string   `xml:"pubDate,omitempty"`
+}
+
+func newRssItem(u *url) *rssItem {
+	item := &rssItem{Link: u.Loc, Guid: u.Loc}
+	if t, err := parseW3CDate(u.LastMod); err == nil {
+		item.PubDate = t.UTC().Format(time.RFC1123Z)
+	}
+	return item
+}
+
+type atomFeed struct {
+	XMLName xml.Name `xml:"feed"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Id      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Author  struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// 网址没有标题，使用网址作为 <title>
+type atomEntry struct {
+	XMLName xml.Name `xml:"entry"`
+	Id      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+}
+
+func newAtomEntry(u *url, updated string) *atomEntry {
+	entry := &atomEntry{Id: u.Loc, Title: u.Loc, Updated: updated, Link: atomLink{Href: u.Loc}}
+	if t, err := parseW3CDate(u.LastMod); err == nil {
+		entry.Updated = t.UTC().Format(time.RFC3339)
+	}
+	return entry
+}
@@ -0,0 +1,127 @@
+package gositemap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSitemap_Render(t *testing.T) {
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.SetFeedTitle("douyacun")
+	st.AppendUrl(NewUrl().SetLoc("/a.html").SetLastmod(time.Date(2020, 4, 19, 8, 0, 0, 0, time.UTC)))
+	st.AppendUrl(NewUrl().SetLoc("/b.html"))
+
+	st.SetFormat(TxtFormat)
+	data, err := st.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "https://www.douyacun.com/a.html\nhttps://www.douyacun.com/b.html\n" {
+		t.Fatalf("unexpected txt %q", data)
+	}
+
+	st.SetFormat(RssFormat)
+	if data, err = st.Render(); err != nil {
+		t.Fatal(err)
+	}
+	var rss rssFeed
+	if err = xml.Unmarshal(data, &rss); err != nil {
+		t.Fatal(err)
+	}
+	if rss.Channel.Title != "douyacun" || len(rss.Channel.Items) != 2 ||
+		rss.Channel.Items[0].PubDate != "Sun, 19 Apr 2020 08:00:00 +0000" || rss.Channel.Items[1].PubDate != "" {
+		t.Fatalf("unexpected rss %s", data)
+	}
+
+	st.SetFormat(AtomFormat)
+	if data, err = st.Render(); err != nil {
+		t.Fatal(err)
+	}
+	var atom atomFeed
+	if err = xml.Unmarshal(data, &atom); err != nil {
+		t.Fatal(err)
+	}
+	if atom.Id != "https://www.douyacun.com/sitemap.atom" || atom.Updated != "2020-04-19T08:00:00Z" || len(atom.Entries) != 2 ||
+		atom.Entries[1].Updated != atom.Updated || atom.Entries[1].Link.Href != "https://www.douyacun.com/b.html" {
+		t.Fatalf("unexpected atom %s", data)
+	}
+
+	st.SetMaxLinks(1)
+	for _, format := range []Format{TxtFormat, RssFormat, AtomFormat} {
+		st.SetFormat(format)
+		if _, err = st.Render(); err != TooMuchLinksError {
+			t.Fatalf("format %d: expect TooMuchLinksError, got %v", format, err)
+		}
+	}
+}
+
+func TestSitemap_StorageFormat(t *testing.T) {
+	for _, format := range []Format{TxtFormat, RssFormat, AtomFormat} {
+		for _, pretty := range []bool{false, true} {
+			storage := NewMemoryStorage()
+			st := NewSiteMap()
+			st.SetStorage(storage)
+			st.SetFormat(format)
+			st.SetPretty(pretty)
+			st.SetMaxBytes(2048)
+			for i := 0; i < 60; i++ {
+				st.AppendUrl(NewUrl().SetLoc(fmt.Sprintf("/article/%d.html", i)).SetLastmod(time.Now()))
+			}
+			filename, err := st.Storage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filename != "sitemap.xml" {
+				t.Fatalf("format %d: expect sitemapindex, got %s", format, filename)
+			}
+			index, err := readFile(storage, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names, err := storage.List("sitemap-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(names) < 2 {
+				t.Fatalf("format %d: expect shards, got %v", format, names)
+			}
+			for _, name := range names {
+				if !strings.HasSuffix(name, format.ext()) || !strings.Contains(string(index), name) {
+					t.Fatalf("format %d: unexpected shard %s", format, name)
+				}
+				data, err := readFile(storage, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(data) > 2048 {
+					t.Fatalf("format %d: shard %s too large: %d", format, name, len(data))
+				}
+			}
+		}
+	}
+}
+
+func TestHandler_Format(t *testing.T) {
+	st := NewSiteMap()
+	st.SetFormat(TxtFormat)
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/"))
+	w := httptest.NewRecorder()
+	NewHandler(st).ServeHTTP(w, httptest.NewRequest("GET", "/sitemap.txt", nil))
+	if w.Code != 200 || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" || w.Body.String() != "https://www.douyacun.com/\n" {
+		t.Fatalf("unexpected response %d %s %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
+
+func TestStreamSiteMap_Format(t *testing.T) {
+	st := NewStreamSiteMap()
+	st.SetStorage(NewMemoryStorage())
+	st.SetFormat(TxtFormat)
+	if err := st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/")); err != StreamFormatError {
+		t.Fatalf("expect StreamFormatError, got %v", err)
+	}
+}
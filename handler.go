@@ -0,0 +1,215 @@
+package gositemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 直接由 http 服务提供sitemap
+// 请求路径的文件名为 filename 时返回sitemap，拆分成多个分片时返回 sitemapindex，分片为 sitemap-1.xml、sitemap-2.xml ...
+// SetFormat 时sitemap和分片的扩展名由 format 决定，如 sitemap.txt、sitemap-1.txt
+// 文件名加上 .gz 时返回 gzip 压缩的文件，客户端支持时以 Content-Encoding: gzip 压缩传输
+// 渲染结果会被缓存，直到通过 AppendUrl、Append 修改了内容，新闻sitemap中有新闻过期时也会重新渲染
+type handler struct {
+	mu      sync.Mutex
+	source  func() (*sitemap, error)
+	index   *siteMapIndex
+	owner   interface{}
+	version uint64
+	// 新闻sitemap中最早的一篇新闻过期的时间，之后缓存失效，见 SetNewsMode
+	expires time.Time
+	files   map[string]*renderedFile
+}
+
+// 提供 st 中的网址
+func NewHandler(st *sitemap) *handler {
+	return NewHandlerFunc(func() (*sitemap, error) {
+		return st, nil
+	})
+}
+
+// 每次请求时调用 fn 获取sitemap，返回的sitemap没有变化时使用缓存
+func NewHandlerFunc(fn func() (*sitemap, error)) *handler {
+	return &handler{source: fn}
+}
+
+// 提供 sitemapindex，任意路径都返回 index
+func NewIndexHandler(index *siteMapIndex) *handler {
+	return &handler{index: index}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	files, err := h.load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := path.Base(r.URL.Path)
+	compressed := strings.HasSuffix(name, ".gz")
+	f, ok := files[strings.TrimSuffix(name, ".gz")]
+	if !ok {
+		if f, ok = files[""]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	var (
+		content     = f.data
+		etag        = f.etag
+		contentType = f.contentType
+	)
+	w.Header().Set("Vary", "Accept-Encoding")
+	if compressed {
+		content, etag, contentType = f.gzipped(), f.etag+"-gz", "application/gzip"
+	} else if acceptsGzip(r) {
+		content, etag = f.gzipped(), f.etag+"-gz"
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", strconv.Quote(etag))
+	http.ServeContent(w, r, name, f.modTime, bytes.NewReader(content))
+}
+
+// 返回缓存的渲染结果，内容变化时重新渲染
+func (h *handler) load() (map[string]*renderedFile, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.index != nil {
+		if h.files != nil && h.version == h.index.version {
+			return h.files, nil
+		}
+		data, err := h.index.ToXml()
+		if err != nil {
+			return nil, err
+		}
+		h.version = h.index.version
+		h.files = map[string]*renderedFile{"": newRenderedFile(data, h.index.lastMod())}
+		return h.files, nil
+	}
+
+	owner, err := h.source()
+	if err != nil {
+		return nil, err
+	}
+	if h.files != nil && h.owner == owner && h.version == owner.currentVersion() &&
+		(h.expires.IsZero() || time.Now().Before(h.expires)) {
+		return h.files, nil
+	}
+	st := owner.snapshot()
+	shards, err := st.Split()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*renderedFile)
+	if len(shards) == 1 {
+		data, err := st.Render()
+		if err != nil {
+			return nil, err
+		}
+		files[st.formatFilename()] = newRenderedFile(data, st.lastMod()).setFormat(st.format)
+	} else {
+		index := NewSiteMapIndex()
+		for _, shard := range shards {
+			data, err := shard.Render()
+			if err != nil {
+				return nil, err
+			}
+			files[shard.filename] = newRenderedFile(data, shard.lastMod()).setFormat(st.format)
+			index.Append(st.absUrl(shard.filename), shard.lastMod())
+		}
+		data, err := index.ToXml()
+		if err != nil {
+			return nil, err
+		}
+		files[st.filename] = newRenderedFile(data, index.lastMod())
+	}
+	h.owner, h.version, h.files = owner, st.version, files
+	h.expires = time.Time{}
+	if st.newsMode {
+		h.expires = st.newsExpiry()
+	}
+	return files, nil
+}
+
+// sitemapindex 中最新的 lastmod，没有时为零值
+func (s *siteMapIndex) lastMod() (newest time.Time) {
+	for _, m := range s.SiteMap {
+		if t, err := parseW3CDate(m.LastMod); err == nil && t.After(newest) {
+			newest = t
+		}
+	}
+	return
+}
+
+// 渲染后的文件，gzip 压缩的内容在第一次使用时生成
+type renderedFile struct {
+	data        []byte
+	etag        string
+	contentType string
+	modTime     time.Time
+	once        sync.Once
+	gzip        []byte
+}
+
+// modTime 为零值时使用渲染的时间
+func newRenderedFile(data []byte, modTime time.Time) *renderedFile {
+	sum := sha256.Sum256(data)
+	if modTime.IsZero() || modTime.After(time.Now()) {
+		modTime = time.Now()
+	}
+	return &renderedFile{
+		data:        data,
+		etag:        hex.EncodeToString(sum[:8]),
+		contentType: XmlFormat.contentType(),
+		modTime:     modTime,
+	}
+}
+
+func (f *renderedFile) setFormat(format Format) *renderedFile {
+	f.contentType = format.contentType()
+	return f
+}
+
+func (f *renderedFile) gzipped() []byte {
+	f.once.Do(func() {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, _ = gw.Write(f.data)
+		_ = gw.Close()
+		f.gzip = buf.Bytes()
+	})
+	return f.gzip
+}
+
+// Accept-Encoding 中包含 gzip 且 q 不为 0
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.TrimSpace(fields[0])
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		return q > 0
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package gositemap
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.SetMaxLinks(2)
+	lastmod := time.Date(2020, 4, 19, 9, 28, 33, 0, time.UTC)
+	for _, loc := range []string{"/a", "/b", "/c"} {
+		st.AppendUrl(NewUrl().SetLoc(loc).SetLastmod(lastmod))
+	}
+	h := NewHandler(st)
+	get := func(target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/sitemap.xml", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/xml; charset=utf-8" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if !strings.Contains(w.Body.String(), "<sitemapindex") || !strings.Contains(w.Body.String(), "sitemap-2.xml") {
+		t.Fatalf("unexpected index %s", w.Body)
+	}
+	if w.Header().Get("Last-Modified") != "Sun, 19 Apr 2020 09:28:33 GMT" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("unexpected header %v", w.Header())
+	}
+
+	// 条件请求
+	etag := get("/sitemap-2.xml", nil).Header().Get("ETag")
+	if w = get("/sitemap-2.xml", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+	if w = get("/sitemap-2.xml", map[string]string{"If-Modified-Since": "Sun, 19 Apr 2020 09:28:33 GMT"}); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+
+	// gzip
+	w = get("/sitemap-2.xml", map[string]string{"Accept-Encoding": "br, gzip;q=0.8"})
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("ETag") == etag {
+		t.Fatalf("unexpected header %v", w.Header())
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(gr)
+	if !strings.Contains(string(data), "https://www.douyacun.com/c") {
+		t.Fatalf("unexpected shard %s", data)
+	}
+	if w = get("/sitemap-1.xml", map[string]string{"Accept-Encoding": "gzip;q=0"}); w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("unexpected gzip encoding")
+	}
+	if w = get("/sitemap-1.xml.gz", nil); w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("unexpected header %v", w.Header())
+	}
+
+	// 内容变化后重新渲染
+	st.AppendUrl(NewUrl().SetLoc("/d").SetLastmod(lastmod))
+	if w = get("/sitemap-2.xml", map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/d</loc>") {
+		t.Fatalf("expected updated shard, got %d %s", w.Code, w.Body)
+	}
+	if w = get("/sitemap-9.xml", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/sitemap.xml", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestNewHandlerFunc(t *testing.T) {
+	calls := 0
+	h := NewHandlerFunc(func() (*sitemap, error) {
+		calls++
+		st := NewSiteMap()
+		st.AppendUrl(NewUrl().SetLoc("/"))
+		return st, nil
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	if w.Code != http.StatusOK || calls != 1 || !strings.Contains(w.Body.String(), "<urlset") {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,218 @@
+package gositemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	scriptRegexp = regexp.MustCompile(`(?is)<script\b.*?</script\s*>`)
+	styleRegexp  = regexp.MustCompile(`(?is)<style\b.*?</style\s*>`)
+)
+
+// 网页中与sitemap相关的信息
+type page struct {
+	title       string
+	description string
+	canonical   string
+	noindex     bool
+	nofollow    bool
+	links       []string
+	images      []*image
+	videos      []*video
+}
+
+// 解析html，网页中的地址都会以 base 补全成绝对地址
+// 使用非严格模式的 xml.Decoder 解析，遇到无法解析的内容时返回已经解析到的部分
+func parseHTML(r io.Reader, base *neturl.URL) (*page, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = scriptRegexp.ReplaceAll(data, nil)
+	data = styleRegexp.ReplaceAll(data, nil)
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var (
+		p       = &page{}
+		inTitle bool
+		_video  *video
+		seen    = make(map[string]bool)
+	)
+	resolve := func(ref string) string {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "data:") {
+			return ""
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return ""
+		}
+		u.Fragment = ""
+		return u.String()
+	}
+	// 同一张图片只保留第一次出现的位置
+	appendImage := func(loc, title, caption string) {
+		if loc == "" || seen[loc] {
+			return
+		}
+		seen[loc] = true
+		p.images = append(p.images, NewImage().SetLoc(loc).SetTitle(title).SetCaption(caption))
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			attr := func(name string) string {
+				for _, a := range t.Attr {
+					if strings.EqualFold(a.Name.Local, name) {
+						return a.Value
+					}
+				}
+				return ""
+			}
+			switch strings.ToLower(t.Name.Local) {
+			case "title":
+				inTitle = p.title == ""
+			case "base":
+				if u, err := base.Parse(attr("href")); err == nil && attr("href") != "" {
+					base = u
+				}
+			case "meta":
+				switch strings.ToLower(attr("name")) {
+				case "robots":
+					for _, directive := range strings.Split(strings.ToLower(attr("content")), ",") {
+						switch strings.TrimSpace(directive) {
+						case "noindex":
+							p.noindex = true
+						case "nofollow":
+							p.nofollow = true
+						case "none":
+							p.noindex, p.nofollow = true, true
+						}
+					}
+				case "description":
+					p.description = strings.TrimSpace(attr("content"))
+				}
+				switch strings.ToLower(attr("property")) {
+				case "og:image", "og:image:url", "og:image:secure_url":
+					appendImage(resolve(attr("content")), "", "")
+				}
+			case "link":
+				if strings.EqualFold(attr("rel"), "canonical") {
+					p.canonical = resolve(attr("href"))
+				}
+			case "a", "area":
+				if strings.Contains(strings.ToLower(attr("rel")), "nofollow") {
+					continue
+				}
+				if href := resolve(attr("href")); href != "" {
+					p.links = append(p.links, href)
+				}
+			case "img":
+				appendImage(resolve(attr("src")), attr("title"), attr("alt"))
+				for _, src := range parseSrcset(attr("srcset")) {
+					appendImage(resolve(src), attr("title"), attr("alt"))
+				}
+			case "video":
+				_video = NewVideo().
+					SetTitle(attr("title")).
+					SetThumbnailLoc(resolve(attr("poster"))).
+					SetContentLoc(resolve(attr("src")))
+				p.videos = append(p.videos, _video)
+			case "source":
+				if _video != nil && _video.ContentLoc == "" {
+					_video.SetContentLoc(resolve(attr("src")))
+				}
+				// <picture> 中的 <source srcset>
+				if _video == nil {
+					for _, src := range parseSrcset(attr("srcset")) {
+						appendImage(resolve(src), "", "")
+					}
+				}
+			}
+		case xml.EndElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "title":
+				inTitle = false
+			case "video":
+				_video = nil
+			}
+		case xml.CharData:
+			if inTitle {
+				p.title += string(t)
+			}
+		}
+	}
+	p.title = strings.TrimSpace(p.title)
+	return p, nil
+}
+
+// srcset 中的图片地址，如 "a.jpg 1x, b.jpg 2x"，地址本身可以包含逗号
+func parseSrcset(srcset string) []string {
+	var list []string
+	for s := strings.TrimSpace(srcset); s != ""; s = strings.TrimLeft(s, " \t\n\r\f,") {
+		end := strings.IndexAny(s, " \t\n\r\f")
+		if end < 0 {
+			end = len(s)
+		}
+		src := s[:end]
+		s = s[end:]
+		if strings.HasSuffix(src, ",") {
+			// 没有描述符
+			src = strings.TrimRight(src, ",")
+		} else if i := strings.IndexByte(s, ','); i >= 0 {
+			s = s[i:]
+		} else {
+			s = ""
+		}
+		if src != "" {
+			list = append(list, src)
+		}
+	}
+	return list
+}
+
+// 从网页的html中提取图片，包括 <img> 的 src、srcset，<picture> 中 <source> 的 srcset 以及 og:image，
+// 相对地址以网址的 loc 补全，已经包含的图片不会重复添加
+// 超过 1000 张时返回 TooManyImagesError，此前的图片已经添加
+func (u *url) AppendImagesFromHTML(r io.Reader) error {
+	base, err := neturl.Parse(u.Loc)
+	if err != nil || !base.IsAbs() {
+		return &FieldError{Loc: u.Loc, Field: "loc", Value: u.Loc, Err: InvalidLocError}
+	}
+	p, err := parseHTML(r, base)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, token := range u.Token {
+		if i, ok := token.(*image); ok {
+			existing[i.Loc] = true
+		}
+	}
+	for _, i := range p.images {
+		if existing[i.Loc] {
+			continue
+		}
+		if err = u.AppendImage(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package gositemap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUrl_AppendImage(t *testing.T) {
+	u := NewUrl().SetLoc("https://www.douyacun.com/images.html")
+	if err := u.AppendImage(NewImage()); !errors.Is(err, MissingFieldError) {
+		t.Fatalf("expect MissingFieldError, got %v", err)
+	}
+	for i := 0; i < MaxImagesPerUrl; i++ {
+		if err := u.AppendImage(NewImage().SetLoc(fmt.Sprintf("https://www.douyacun.com/%d.jpg", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := u.AppendImage(NewImage().SetLoc("https://www.douyacun.com/more.jpg")); !errors.Is(err, TooManyImagesError) {
+		t.Fatalf("expect TooManyImagesError, got %v", err)
+	}
+	if u.images() != MaxImagesPerUrl {
+		t.Fatalf("expect %d images, got %d", MaxImagesPerUrl, u.images())
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	for srcset, want := range map[string][]string{
+		"":                                 nil,
+		"a.jpg":                            {"a.jpg"},
+		"a.jpg 1x, b.jpg 2x":               {"a.jpg", "b.jpg"},
+		" a.jpg 480w,\n b.jpg 800w ":       {"a.jpg", "b.jpg"},
+		"a.jpg,b.jpg 2x":                   {"a.jpg,b.jpg"},
+		"a.jpg, b.jpg":                     {"a.jpg", "b.jpg"},
+		"/w_100,h_100/a.jpg 1x, /b.jpg 2x": {"/w_100,h_100/a.jpg", "/b.jpg"},
+	} {
+		if got := parseSrcset(srcset); !reflect.DeepEqual(got, want) {
+			t.Fatalf("parseSrcset(%q) = %q, want %q", srcset, got, want)
+		}
+	}
+}
+
+func TestUrl_AppendImagesFromHTML(t *testing.T) {
+	html := `<html><head>
+		<meta property="og:image" content="https://cdn.douyacun.com/cover.jpg">
+		</head><body>
+		<img src="a.jpg" alt="图片A" title="A" srcset="a.jpg 1x, a@2x.jpg 2x">
+		<picture><source srcset="/images/b.webp"><img src="/images/b.jpg"></picture>
+		<img src="data:image/png;base64,AAAA">
+		<img src="https://cdn.douyacun.com/cover.jpg">
+		<video poster="/v.jpg"><source src="/v.mp4"></video>
+		</body></html>`
+	u := NewUrl().SetLoc("https://www.douyacun.com/post/1.html")
+	if err := u.AppendImage(NewImage().SetLoc("https://www.douyacun.com/images/b.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.AppendImagesFromHTML(strings.NewReader(html)); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, token := range u.Token {
+		got = append(got, token.(*image).Loc)
+	}
+	want := []string{
+		"https://www.douyacun.com/images/b.jpg",
+		"https://cdn.douyacun.com/cover.jpg",
+		"https://www.douyacun.com/post/a.jpg",
+		"https://www.douyacun.com/post/a@2x.jpg",
+		"https://www.douyacun.com/images/b.webp",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected images %q", got)
+	}
+	if i := u.Token[2].(*image); i.Caption != "图片A" || i.Title != "A" {
+		t.Fatalf("unexpected image %+v", i)
+	}
+	if u.xmlns&ImageXmlNS == 0 {
+		t.Fatalf("image namespace not set")
+	}
+
+	if err := NewUrl().SetLoc("/post/1.html").AppendImagesFromHTML(strings.NewReader(html)); !errors.Is(err, InvalidLocError) {
+		t.Fatalf("expect InvalidLocError, got %v", err)
+	}
+}
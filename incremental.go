@@ -0,0 +1,236 @@
+package gositemap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// 增量生成的状态，记录每个分片包含的网址和内容摘要
+type manifest struct {
+	Shards []*manifestShard `json:"shards"`
+}
+
+type manifestShard struct {
+	// 实际写入的文件名，sitemap-1.xml 或 sitemap-1.xml.gz
+	Filename string   `json:"filename"`
+	Hash     string   `json:"hash"`
+	LastMod  string   `json:"lastmod"`
+	Urls     []string `json:"urls"`
+}
+
+// 读取状态文件，不存在时返回空的状态
+func loadManifest(storage Storage, name string) (*manifest, error) {
+	data, err := readFile(storage, name)
+	if os.IsNotExist(err) {
+		return &manifest{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	m := &manifest{}
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *manifest) save(storage Storage, name string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(storage, name, data, false)
+}
+
+// 增量生成
+// 网址保留在上次所在的分片中，新增的网址优先放入最后一个分片，放不下时生成新的分片
+// 内容没有变化的分片不会重写，sitemapindex 中也只更新变化分片的 lastmod
+// changed 为重写的分片中的网址，没有重写或删除任何文件时 updated 为 false
+func (s *sitemap) storageIncremental() (filename string, changed []string, updated bool, err error) {
+	storage := s.store()
+	var prev *manifest
+	if prev, err = loadManifest(storage, s.manifestFilename()); err != nil {
+		return
+	}
+	var shards []*sitemap
+	if shards, err = s.assign(prev); err != nil {
+		return
+	}
+
+	var (
+		next  = &manifest{}
+		index = NewSiteMapIndex()
+		now   = time.Now()
+		kept  = make(map[string]bool)
+		olds  = make(map[string]*manifestShard)
+	)
+	for _, old := range prev.Shards {
+		olds[old.Filename] = old
+	}
+	for _, shard := range shards {
+		var data []byte
+		if data, err = shard.Render(); err != nil {
+			return
+		}
+		sum := sha256.Sum256(data)
+		entry := &manifestShard{
+			Filename: shard.outputFilename(),
+			Hash:     hex.EncodeToString(sum[:]),
+			LastMod:  now.Format(time.RFC3339),
+		}
+		for _, token := range shard.Token {
+			entry.Urls = append(entry.Urls, token.(*url).Loc)
+		}
+		old := olds[entry.Filename]
+		if old != nil && old.Hash == entry.Hash && exists(storage, old.Filename) {
+			entry.LastMod = old.LastMod
+		} else if _, err = shard.write(); err != nil {
+			return
+		} else {
+			updated = true
+			changed = append(changed, entry.Urls...)
+			// 优先使用分片中网址最新的 lastmod，
+			// 没有时或者不比上次新时（如删除了网址）使用重写的时间，保证搜索引擎能发现分片的变化
+			if lastMod := shard.lastMod(); !lastMod.IsZero() && (old == nil || newerThan(lastMod, old.LastMod)) {
+				entry.LastMod = lastMod.Format(time.RFC3339)
+			}
+		}
+		kept[entry.Filename] = true
+		next.Shards = append(next.Shards, entry)
+		index.SiteMap = append(index.SiteMap, Map{Loc: s.absUrl(entry.Filename), LastMod: entry.LastMod})
+	}
+
+	filename = s.filename
+	var data []byte
+	if data, err = index.ToXml(); err != nil {
+		return
+	}
+	if current, e := readFile(storage, filename); e != nil || !bytes.Equal(current, data) {
+		if err = index.Save(storage, filename); err != nil {
+			return
+		}
+		updated = true
+	}
+	if err = next.save(storage, s.manifestFilename()); err != nil {
+		return
+	}
+	// sitemapindex 不再引用之后才删除不再使用的分片，避免已发布的 sitemapindex 指向不存在的文件
+	for _, old := range prev.Shards {
+		if !kept[old.Filename] {
+			if e := storage.Delete(old.Filename); e != nil && !os.IsNotExist(e) {
+				err = e
+				return
+			}
+			updated = true
+		}
+	}
+	return
+}
+
+// t 比 lastmod 新，lastmod 无法解析时也认为更新
+func newerThan(t time.Time, lastmod string) bool {
+	prev, err := parseW3CDate(lastmod)
+	return err != nil || t.After(prev)
+}
+
+// 按照上次的状态分配网址，上次的分片沿用原来的文件名，之后为新增的分片
+// 网址全部被删除的分片不会返回
+func (s *sitemap) assign(prev *manifest) ([]*sitemap, error) {
+	overhead, err := s.overhead()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		owner  = make(map[string]int)
+		groups = make([][]*url, len(prev.Shards))
+		fresh  []*url
+		used   = make(map[string]bool)
+	)
+	for i, shard := range prev.Shards {
+		for _, loc := range shard.Urls {
+			owner[loc] = i
+		}
+		used[strings.TrimSuffix(shard.Filename, ".gz")] = true
+	}
+	for _, token := range s.Token {
+		u := token.(*url)
+		if i, ok := owner[u.Loc]; ok {
+			groups[i] = append(groups[i], u)
+		} else {
+			fresh = append(fresh, u)
+		}
+	}
+
+	var (
+		shards []*sitemap
+		sizes  []int
+	)
+	// 尝试将网址放入分片，超过 maxLinks 或 maxBytes 时返回false
+	fit := func(i int, u *url) (bool, error) {
+		data, err := marshalToken(u, s.pretty)
+		if err != nil {
+			return false, err
+		}
+		if len(shards[i].Token) >= s.maxLinks || sizes[i]+len(data) > s.maxBytes {
+			if len(shards[i].Token) == 0 {
+				return false, TooLargeError
+			}
+			return false, nil
+		}
+		shards[i].add(u)
+		sizes[i] += len(data)
+		return true, nil
+	}
+	for i, group := range groups {
+		shard := s.newShard(0)
+		shard.filename = strings.TrimSuffix(prev.Shards[i].Filename, ".gz")
+		shards, sizes = append(shards, shard), append(sizes, overhead)
+		for _, u := range group {
+			ok, err := fit(i, u)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				fresh = append(fresh, u)
+			}
+		}
+	}
+
+	n := 1
+	for last := len(shards) - 1; len(fresh) > 0; {
+		if last >= 0 && len(shards[last].Token) > 0 {
+			ok, err := fit(last, fresh[0])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				fresh = fresh[1:]
+				continue
+			}
+		}
+		for used[s.shardFilename(n)] {
+			n++
+		}
+		used[s.shardFilename(n)] = true
+		shards, sizes = append(shards, s.newShard(n)), append(sizes, overhead)
+		last = len(shards) - 1
+		if ok, err := fit(last, fresh[0]); err != nil {
+			return nil, err
+		} else if ok {
+			fresh = fresh[1:]
+		}
+	}
+
+	// 去掉已经没有网址的分片
+	result := shards[:0]
+	for _, shard := range shards {
+		if len(shard.Token) > 0 {
+			result = append(result, shard)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,176 @@
+package gositemap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSitemap_StorageIncremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lastmod := time.Date(2020, 4, 19, 0, 0, 0, 0, time.UTC)
+	build := func(locs ...string) *sitemap {
+		st := NewSiteMap()
+		st.SetDefaultHost("https://www.douyacun.com")
+		st.SetPublicPath(dir)
+		st.SetMaxLinks(2)
+		st.SetIncremental(true)
+		for _, loc := range locs {
+			st.AppendUrl(NewUrl().SetLoc(loc).SetLastmod(lastmod))
+		}
+		return st
+	}
+	// 将文件的修改时间改到过去，用于判断是否被重写
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	touch := func() {
+		files, _ := ioutil.ReadDir(dir)
+		for _, f := range files {
+			_ = os.Chtimes(path.Join(dir, f.Name()), past, past)
+		}
+	}
+	rewritten := func() (names []string) {
+		files, _ := ioutil.ReadDir(dir)
+		for _, f := range files {
+			if strings.HasSuffix(f.Name(), ".xml") && !f.ModTime().Equal(past) {
+				names = append(names, f.Name())
+			}
+		}
+		return
+	}
+
+	if _, err = build("/a", "/b", "/c", "/d", "/e").Storage(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rewritten(), " "); got != "sitemap-1.xml sitemap-2.xml sitemap-3.xml sitemap.xml" {
+		t.Fatalf("unexpected files %s", got)
+	}
+
+	// 没有变化时不重写任何文件
+	touch()
+	if _, err = build("/a", "/b", "/c", "/d", "/e").Storage(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rewritten(); len(got) != 0 {
+		t.Fatalf("expected no rewrite, got %v", got)
+	}
+
+	// 新增的网址放入最后一个分片，删除网址只重写所在的分片
+	touch()
+	if _, err = build("/a", "/b", "/d", "/e", "/f").Storage(); err != nil {
+		t.Fatal(err)
+	}
+	// sitemapindex 中的 lastmod 精确到秒，同一秒内重新生成时内容不变
+	if got := strings.TrimSuffix(strings.Join(rewritten(), " "), " sitemap.xml"); got != "sitemap-2.xml sitemap-3.xml" {
+		t.Fatalf("unexpected rewrite %s", got)
+	}
+	data, err := ioutil.ReadFile(path.Join(dir, "sitemap-3.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "/e</loc>") || !strings.Contains(string(data), "/f</loc>") {
+		t.Fatalf("unexpected shard %s", data)
+	}
+
+	// 分片中的网址全部删除时删除文件
+	touch()
+	if _, err = build("/d", "/e", "/f").Storage(); err != nil {
+		t.Fatal(err)
+	}
+	if exists(NewFileStorage(dir), "sitemap-1.xml") {
+		t.Fatalf("expected sitemap-1.xml removed")
+	}
+	index, err := ioutil.ReadFile(path.Join(dir, "sitemap.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(index), "sitemap-1.xml") || strings.Count(string(index), "<lastmod>") != 2 {
+		t.Fatalf("unexpected index %s", index)
+	}
+
+	// 不再使用的分片已经不存在时不报错
+	if err = os.Remove(path.Join(dir, "sitemap-2.xml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = build("/f").Storage(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 写入 fail 时返回错误
+type failingStorage struct {
+	Storage
+	fail string
+}
+
+func (s *failingStorage) Create(name string) (io.WriteCloser, error) {
+	if name == s.fail {
+		return nil, errors.New("create " + name + " failed")
+	}
+	return s.Storage.Create(name)
+}
+
+func TestSitemap_StorageIncrementalIndex(t *testing.T) {
+	storage := &failingStorage{Storage: NewMemoryStorage()}
+	lastmod := time.Date(2020, 4, 19, 0, 0, 0, 0, time.UTC)
+	build := func(locs ...string) *sitemap {
+		st := NewSiteMap()
+		st.SetDefaultHost("https://www.douyacun.com")
+		st.SetStorage(storage)
+		st.SetMaxLinks(2)
+		st.SetIncremental(true)
+		for _, loc := range locs {
+			st.AppendUrl(NewUrl().SetLoc(loc).SetLastmod(lastmod))
+		}
+		return st
+	}
+	readIndex := func() *siteMapIndex {
+		data, err := readFile(storage, "sitemap.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := ParseSiteMapIndex(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+
+	if _, err := build("/a", "/b", "/c", "/d").Storage(); err != nil {
+		t.Fatal(err)
+	}
+	// 删除网址后分片被重写，网址的 lastmod 没有变化时使用重写的时间
+	if _, err := build("/a", "/b", "/c").Storage(); err != nil {
+		t.Fatal(err)
+	}
+	index := readIndex()
+	if len(index.SiteMap) != 2 || index.SiteMap[0].LastMod != "2020-04-19T00:00:00Z" || index.SiteMap[1].LastMod == "2020-04-19T00:00:00Z" {
+		t.Fatalf("unexpected index %+v", index.SiteMap)
+	}
+
+	// sitemapindex 写入失败时不删除仍被引用的分片
+	storage.fail = "sitemap.xml"
+	if _, err := build("/a", "/b").Storage(); err == nil {
+		t.Fatalf("expected error")
+	}
+	if !exists(storage, "sitemap-2.xml") {
+		t.Fatalf("sitemap-2.xml removed before sitemapindex was saved")
+	}
+	storage.fail = ""
+	if _, err := build("/a", "/b").Storage(); err != nil {
+		t.Fatal(err)
+	}
+	if exists(storage, "sitemap-2.xml") || len(readIndex().SiteMap) != 1 {
+		t.Fatalf("expected sitemap-2.xml removed")
+	}
+}
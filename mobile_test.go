@@ -0,0 +1,32 @@
+package gositemap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUrl_SetMobile(t *testing.T) {
+	st := NewSiteMap()
+	st.AppendUrl(NewUrl().SetLoc("https://www.example.com/m/").SetMobile(true).SetMobile(true))
+	st.AppendUrl(NewUrl().SetLoc("https://www.example.com/").SetMobile(false))
+	data, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `xmlns:mobile="http://www.google.com/schemas/sitemap-mobile/1.0"`) ||
+		strings.Count(string(data), "<mobile:mobile>") != 1 {
+		t.Fatalf("unexpected xml %s", data)
+	}
+
+	parsed, err := ParseSiteMap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Token[0].(*url).Token) != 1 || len(parsed.Token[1].(*url).Token) != 0 || parsed.xmlns&MobileXmlNS == 0 {
+		t.Fatalf("unexpected parsed urls %+v", parsed.Token)
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
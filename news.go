@@ -3,22 +3,104 @@ package gositemap
 import (
 	"encoding/xml"
 	"errors"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
+const (
+	// MaxStockTickers defines max stock tickers per news
+	MaxStockTickers = 5
+)
+
 var (
-	InvalidLanguageError = errors.New("语言代码错误")
+	InvalidLanguageError     = errors.New("语言代码错误，需要使用 ISO 639 语言代码，简体中文为 zh-cn，繁体中文为 zh-tw")
+	TooManyStockTickersError = errors.New("每篇新闻最多包含 5 个股票代码")
+	InvalidStockTickerError  = errors.New("股票代码需要以交易所名称开头，如 NASDAQ:AMAT")
+)
+
+var stockTickerRegexp = regexp.MustCompile(`^[A-Za-z0-9.]+:[A-Za-z0-9.\-]+$`)
+
+// 新闻的访问权限
+type NewsAccess string
+
+const (
+	// 需要付费订阅才能阅读
+	Subscription NewsAccess = "Subscription"
+	// 需要注册才能阅读
+	Registration NewsAccess = "Registration"
 )
 
+// 新闻的类型
+type Genre string
+
+const (
+	PressRelease  Genre = "PressRelease"
+	Satire        Genre = "Satire"
+	Blog          Genre = "Blog"
+	OpEd          Genre = "OpEd"
+	Opinion       Genre = "Opinion"
+	UserGenerated Genre = "UserGenerated"
+)
+
+// 字段的顺序与 sitemap-news/0.9 xsd 中的顺序一致
 type news struct {
-	XMLName         xml.Name `xml:"news:news"`
-	Name            string   `xml:"news:publication>news:name"`
-	Language        string   `xml:"news:publication>news:language"`
-	PublicationDate string   `xml:"news:publication_date"`
-	Title           string   `xml:"news:title"`
+	validation
+	XMLName         xml.Name   `xml:"news:news"`
+	Name            string     `xml:"news:publication>news:name"`
+	Language        string     `xml:"news:publication>news:language"`
+	Access          NewsAccess `xml:"news:access,omitempty"`
+	Genres          string     `xml:"news:genres,omitempty"`
+	PublicationDate string     `xml:"news:publication_date"`
+	Title           string     `xml:"news:title"`
+	Keywords        string     `xml:"news:keywords,omitempty"`
+	StockTickers    string     `xml:"news:stock_tickers,omitempty"`
+}
+
+// ISO 639-1 两个字母的语言代码 https://www.loc.gov/standards/iso639-2/php/code_list.php
+var languages = []string{
+	"aa", "ab", "ae", "af", "ak", "am", "an", "ar", "as", "av", "ay", "az", "ba", "be", "bg", "bh", "bi", "bm", "bn", "bo",
+	"br", "bs", "ca", "ce", "ch", "co", "cr", "cs", "cu", "cv", "cy", "da", "de", "dv", "dz", "ee", "el", "en", "eo", "es",
+	"et", "eu", "fa", "ff", "fi", "fj", "fo", "fr", "fy", "ga", "gd", "gl", "gn", "gu", "gv", "ha", "he", "hi", "ho", "hr",
+	"ht", "hu", "hy", "hz", "ia", "id", "ie", "ig", "ii", "ik", "io", "is", "it", "iu", "ja", "jv", "ka", "kg", "ki", "kj",
+	"kk", "kl", "km", "kn", "ko", "kr", "ks", "ku", "kv", "kw", "ky", "la", "lb", "lg", "li", "ln", "lo", "lt", "lu", "lv",
+	"mg", "mh", "mi", "mk", "ml", "mn", "mr", "ms", "mt", "my", "na", "nb", "nd", "ne", "ng", "nl", "nn", "no", "nr", "nv",
+	"ny", "oc", "oj", "om", "or", "os", "pa", "pi", "pl", "ps", "pt", "qu", "rm", "rn", "ro", "ru", "rw", "sa", "sc", "sd",
+	"se", "sg", "si", "sk", "sl", "sm", "sn", "so", "sq", "sr", "ss", "st", "su", "sv", "sw", "ta", "te", "tg", "th", "ti",
+	"tk", "tl", "tn", "to", "tr", "ts", "tt", "tw", "ty", "ug", "uk", "ur", "uz", "ve", "vi", "vo", "wa", "wo", "xh", "yi",
+	"yo", "za", "zh", "zu",
 }
 
-// ISO 639 语言代码 http://www.loc.gov/standards/iso639-2/php/code_list.php
+// ISO 639-2 三个字母的语言代码，包括 B 和 T 两种代码
+var languages3 = []string{
+	"aar", "abk", "ace", "ach", "ada", "ady", "afa", "afh", "afr", "ain", "aka", "akk", "alb", "ale", "alg", "alt", "amh", "ang", "anp", "apa",
+	"ara", "arc", "arg", "arm", "arn", "arp", "art", "arw", "asm", "ast", "ath", "aus", "ava", "ave", "awa", "aym", "aze", "bad", "bai", "bak",
+	"bal", "bam", "ban", "baq", "bas", "bat", "bej", "bel", "bem", "ben", "ber", "bho", "bih", "bik", "bin", "bis", "bla", "bnt", "bod", "bos",
+	"bra", "bre", "btk", "bua", "bug", "bul", "bur", "byn", "cad", "cai", "car", "cat", "cau", "ceb", "cel", "ces", "cha", "chb", "che", "chg",
+	"chi", "chk", "chm", "chn", "cho", "chp", "chr", "chu", "chv", "chy", "cmc", "cnr", "cop", "cor", "cos", "cpe", "cpf", "cpp", "cre", "crh",
+	"crp", "csb", "cus", "cym", "cze", "dak", "dan", "dar", "day", "del", "den", "deu", "dgr", "din", "div", "doi", "dra", "dsb", "dua", "dum",
+	"dut", "dyu", "dzo", "efi", "egy", "eka", "ell", "elx", "eng", "enm", "epo", "est", "eus", "ewe", "ewo", "fan", "fao", "fas", "fat", "fij",
+	"fil", "fin", "fiu", "fon", "fra", "fre", "frm", "fro", "frr", "frs", "fry", "ful", "fur", "gaa", "gay", "gba", "gem", "geo", "ger", "gez",
+	"gil", "gla", "gle", "glg", "glv", "gmh", "goh", "gon", "gor", "got", "grb", "grc", "gre", "grn", "gsw", "guj", "gwi", "hai", "hat", "hau",
+	"haw", "heb", "her", "hil", "him", "hin", "hit", "hmn", "hmo", "hrv", "hsb", "hun", "hup", "hye", "iba", "ibo", "ice", "ido", "iii", "ijo",
+	"iku", "ile", "ilo", "ina", "inc", "ind", "ine", "inh", "ipk", "ira", "iro", "isl", "ita", "jav", "jbo", "jpn", "jpr", "jrb", "kaa", "kab",
+	"kac", "kal", "kam", "kan", "kar", "kas", "kat", "kau", "kaw", "kaz", "kbd", "kha", "khi", "khm", "kho", "kik", "kin", "kir", "kmb", "kok",
+	"kom", "kon", "kor", "kos", "kpe", "krc", "krl", "kro", "kru", "kua", "kum", "kur", "kut", "lad", "lah", "lam", "lao", "lat", "lav", "lez",
+	"lim", "lin", "lit", "lol", "loz", "ltz", "lua", "lub", "lug", "lui", "lun", "luo", "lus", "mac", "mad", "mag", "mah", "mai", "mak", "mal",
+	"man", "mao", "map", "mar", "mas", "may", "mdf", "mdr", "men", "mga", "mic", "min", "mkd", "mkh", "mlg", "mlt", "mnc", "mni", "mno", "moh",
+	"mon", "mos", "mri", "msa", "mun", "mus", "mwl", "mwr", "mya", "myn", "myv", "nah", "nai", "nap", "nau", "nav", "nbl", "nde", "ndo", "nds",
+	"nep", "new", "nia", "nic", "niu", "nld", "nno", "nob", "nog", "non", "nor", "nqo", "nso", "nub", "nwc", "nya", "nym", "nyn", "nyo", "nzi",
+	"oci", "oji", "ori", "orm", "osa", "oss", "ota", "oto", "paa", "pag", "pal", "pam", "pan", "pap", "pau", "peo", "per", "phi", "phn", "pli",
+	"pol", "pon", "por", "pra", "pro", "pus", "que", "raj", "rap", "rar", "roa", "roh", "rom", "ron", "rum", "run", "rup", "rus", "sad", "sag",
+	"sah", "sai", "sal", "sam", "san", "sas", "sat", "scn", "sco", "sel", "sem", "sga", "sgn", "shn", "sid", "sin", "sio", "sit", "sla", "slk",
+	"slo", "slv", "sma", "sme", "smi", "smj", "smn", "smo", "sms", "sna", "snd", "snk", "sog", "som", "son", "sot", "spa", "sqi", "srd", "srn",
+	"srp", "srr", "ssa", "ssw", "suk", "sun", "sus", "sux", "swa", "swe", "syc", "syr", "tah", "tai", "tam", "tat", "tel", "tem", "ter", "tet",
+	"tgk", "tgl", "tha", "tib", "tig", "tir", "tiv", "tkl", "tlh", "tli", "tmh", "tog", "ton", "tpi", "tsi", "tsn", "tso", "tuk", "tum", "tup",
+	"tur", "tut", "tvl", "twi", "tyv", "udm", "uga", "uig", "ukr", "umb", "urd", "uzb", "vai", "ven", "vie", "vol", "vot", "wak", "wal", "war",
+	"was", "wel", "wen", "wln", "wol", "xal", "xho", "yao", "yap", "yid", "yor", "ypk", "zap", "zbl", "zen", "zgh", "zha", "zho", "znd", "zul",
+	"zun", "zza",
+}
 
 func NewNews() *news {
 	return &news{}
@@ -30,19 +112,13 @@ func (n *news) SetName(name string) *news {
 	return n
 }
 
+// 新闻的语言，ISO 639 语言代码（两个或三个字母），简体中文为 zh-cn，繁体中文为 zh-tw
 func (n *news) SetLanguage(language string) *news {
-	if language != "zh-cn" && language != "zh-tw" {
-		all := []string{"aa", "ab", "af", "ak", "sq", "am", "ar", "an", "hy", "as", "av", "ae", "ay", "az", "ba", "bm", "eu", "be", "bn", "bh", "bi", "bo", "bs", "br", "bg", "my", "ca", "cs", "ch", "ce", "zh", "cu", "cv", "kw", "co", "cr", "cy", "cs", "da", "de", "dv", "nl", "dz", "el", "en", "eo", "et", "eu", "ee", "fo", "fa", "fj", "fi", "fr", "fr", "fy", "ff", "ka", "de", "gd", "ga", "gl", "gv", "el", "gn", "gu", "ht", "ha", "he", "hz", "hi", "ho", "hr", "hu", "hy", "ig", "is", "io", "ii", "iu", "ie", "ia", "id", "ik", "is", "it", "jv", "ja", "kl", "kn", "ks", "ka", "kr", "kk", "km", "ki", "rw", "ky", "kv", "kg", "ko", "kj", "ku", "lo", "la", "lv", "li", "ln", "lt", "lb", "lu", "lg", "mk", "mh", "ml", "mi", "mr", "ms", "mk", "mg", "mt", "mn", "mi", "ms", "my", "na", "nv", "nr", "nd", "ng", "ne", "nl", "nn", "nb", "no", "ny", "oc", "oj", "or", "om", "os", "pa", "fa", "pi", "pl", "pt", "ps", "qu", "rm", "ro", "ro", "rn", "ru", "sg", "sa", "si", "sk", "sk", "sl", "se", "sm", "sn", "sd", "so", "st", "es", "sq", "sc", "sr", "ss", "su", "sw", "sv", "ty", "ta", "tt", "te", "tg", "tl", "th", "bo", "ti", "to", "tn", "ts", "tk", "tr", "tw", "ug", "uk", "ur", "uz", "ve", "vi", "vo", "cy", "wa", "wo", "xh", "yi", "yo", "za", "zh", "zu",}
-		for _, v := range all {
-			if v == language {
-				n.Language = v
-				break
-			}
-		}
-		panic(InvalidLanguageError)
-	} else {
+	if isLanguage(language) {
 		n.Language = language
+		return n
 	}
+	n.addError("news:language", language, InvalidLanguageError)
 	return n
 }
 
@@ -55,3 +131,201 @@ func (n *news) SetTitle(title string) *news {
 	n.Title = title
 	return n
 }
+
+// 访问权限，公开的新闻不需要设置
+func (n *news) SetAccess(access NewsAccess) *news {
+	if access != Subscription && access != Registration {
+		n.addError("news:access", access, InvalidValueError)
+		return n
+	}
+	n.Access = access
+	return n
+}
+
+// 新闻的类型，多个类型以逗号分隔
+func (n *news) SetGenres(genres ...Genre) *news {
+	list := make([]string, 0, len(genres))
+	for _, genre := range genres {
+		if !isGenre(string(genre)) {
+			n.addError("news:genres", genre, InvalidValueError)
+			return n
+		}
+		list = append(list, string(genre))
+	}
+	n.Genres = strings.Join(list, ", ")
+	return n
+}
+
+// 新闻的关键词，以逗号分隔
+func (n *news) SetKeywords(keywords []string) *news {
+	n.Keywords = strings.Join(keywords, ", ")
+	return n
+}
+
+// 新闻中涉及的股票代码，最多 5 个，需要以交易所名称开头，如 NASDAQ:AMAT
+func (n *news) SetStockTickers(tickers []string) *news {
+	if len(tickers) > MaxStockTickers {
+		n.addError("news:stock_tickers", tickers, TooManyStockTickersError)
+		return n
+	}
+	for _, ticker := range tickers {
+		if !stockTickerRegexp.MatchString(ticker) {
+			n.addError("news:stock_tickers", ticker, InvalidStockTickerError)
+			return n
+		}
+	}
+	n.StockTickers = strings.Join(tickers, ", ")
+	return n
+}
+
+// 校验新闻的字段，发布时间不能超过 48 小时
+func (n *news) Validate() error {
+	return n.validate().err()
+}
+
+// ISO 639-1 或 ISO 639-2 语言代码，以及 zh-cn、zh-tw
+func isLanguage(language string) bool {
+	language = strings.ToLower(language)
+	if language == "zh-cn" || language == "zh-tw" {
+		return true
+	}
+	list := languages
+	if len(language) == 3 {
+		list = languages3
+	}
+	i := sort.SearchStrings(list, language)
+	return i < len(list) && list[i] == language
+}
+
+// ISO 639-1 两个字母的语言代码
+func isTwoLetterLanguage(language string) bool {
+	language = strings.ToLower(language)
+	i := sort.SearchStrings(languages, language)
+	return len(language) == 2 && i < len(languages) && languages[i] == language
+}
+
+func isGenre(genre string) bool {
+	switch Genre(genre) {
+	case PressRelease, Satire, Blog, OpEd, Opinion, UserGenerated:
+		return true
+	}
+	return false
+}
+
+// 逗号分隔的列表
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// 网址中最新的新闻发布时间，没有新闻时返回 MissingFieldError
+func publishedAt(u *url) (newest time.Time, err error) {
+	found := false
+	for _, token := range u.Token {
+		n, ok := token.(*news)
+		if !ok {
+			continue
+		}
+		found = true
+		date, e := parseW3CDate(n.PublicationDate)
+		if e != nil {
+			return newest, &FieldError{Loc: u.Loc, Field: "news:publication_date", Value: n.PublicationDate, Err: InvalidDateError}
+		}
+		if date.After(newest) {
+			newest = date
+		}
+	}
+	if !found {
+		return newest, &FieldError{Loc: u.Loc, Field: "news:news", Err: MissingFieldError}
+	}
+	return newest, nil
+}
+
+// 网址中最早的一篇新闻超过 48 小时的时间，没有新闻时为零值
+func (u *urlSet) newsExpiry() (expiry time.Time) {
+	for _, token := range u.Token {
+		for _, t := range token.(*url).Token {
+			n, ok := t.(*news)
+			if !ok {
+				continue
+			}
+			date, err := parseW3CDate(n.PublicationDate)
+			if err != nil {
+				continue
+			}
+			if t := date.Add(MaxNewsAge); expiry.IsZero() || t.Before(expiry) {
+				expiry = t
+			}
+		}
+	}
+	return
+}
+
+// 新闻sitemap中的一篇新闻
+type newsEntry struct {
+	url  int
+	news *news
+	date time.Time
+}
+
+// 逐篇去掉超过 48 小时或者发布时间错误的新闻，超过 1000 篇时保留最新的 1000 篇
+// 去掉新闻之后不再包含新闻的网址也会被去掉，其余网址的顺序不变
+// 部分新闻被去掉的网址替换为副本，原来的网址不会被修改
+func filterNews(tokens []xml.Token, now time.Time) []xml.Token {
+	var (
+		entries []newsEntry
+		total   = make([]int, len(tokens))
+	)
+	for i, token := range tokens {
+		for _, t := range token.(*url).Token {
+			n, ok := t.(*news)
+			if !ok {
+				continue
+			}
+			total[i]++
+			date, err := parseW3CDate(n.PublicationDate)
+			if err != nil || now.Sub(date) > MaxNewsAge {
+				continue
+			}
+			entries = append(entries, newsEntry{url: i, news: n, date: date})
+		}
+	}
+	if len(entries) > MaxNewsPerSitemap {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].date.After(entries[j].date)
+		})
+		entries = entries[:MaxNewsPerSitemap]
+	}
+	kept := make([]map[*news]bool, len(tokens))
+	for _, entry := range entries {
+		if kept[entry.url] == nil {
+			kept[entry.url] = make(map[*news]bool)
+		}
+		kept[entry.url][entry.news] = true
+	}
+	var result []xml.Token
+	for i, token := range tokens {
+		if len(kept[i]) == 0 {
+			continue
+		}
+		if len(kept[i]) == total[i] {
+			result = append(result, token)
+			continue
+		}
+		u := *token.(*url)
+		u.Token = nil
+		for _, t := range token.(*url).Token {
+			if n, ok := t.(*news); ok && !kept[i][n] {
+				continue
+			}
+			u.Token = append(u.Token, t)
+		}
+		result = append(result, &u)
+	}
+	return result
+}
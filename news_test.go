@@ -0,0 +1,203 @@
+package gositemap
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsLanguage(t *testing.T) {
+	for language, want := range map[string]bool{
+		"en":    true,
+		"fr":    true,
+		"zh-cn": true,
+		"ZH-TW": true,
+		"eng":   true,
+		"fra":   true,
+		"fre":   true,
+		"xx":    false,
+		"zh-hk": false,
+		"e":     false,
+		"engl":  false,
+	} {
+		if isLanguage(language) != want {
+			t.Fatalf("isLanguage(%q) != %v", language, want)
+		}
+		n := NewNews().SetLanguage(language)
+		if (n.Language == language) != want {
+			t.Fatalf("SetLanguage(%q) = %q", language, n.Language)
+		}
+	}
+}
+
+func TestNews_Fields(t *testing.T) {
+	n := NewNews().
+		SetName("《示例时报》").
+		SetLanguage("zh-cn").
+		SetAccess(Subscription).
+		SetGenres(PressRelease, Blog).
+		SetPublicationDate(time.Now()).
+		SetTitle("公司 A 和 B 正在进行合并谈判").
+		SetKeywords([]string{"商业", "合并"}).
+		SetStockTickers([]string{"NASDAQ:A", "NASDAQ:B"})
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	u := NewUrl().SetLoc("https://www.douyacun.com/business/article55.html")
+	u.AppendNews(n)
+	st.AppendUrl(u)
+	data, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<news:access>Subscription</news:access><news:genres>PressRelease, Blog</news:genres><news:publication_date>" +
+		n.PublicationDate + "</news:publication_date><news:title>公司 A 和 B 正在进行合并谈判</news:title>" +
+		"<news:keywords>商业, 合并</news:keywords><news:stock_tickers>NASDAQ:A, NASDAQ:B</news:stock_tickers>"
+	if !strings.Contains(string(data), want) {
+		t.Fatalf("unexpected xml %s", data)
+	}
+	parsed, err := ParseSiteMap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := parsed.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Fatalf("round trip mismatch:\n%s\n%s", data, again)
+	}
+
+	invalid := NewNews().
+		SetName("《示例时报》").
+		SetLanguage("en").
+		SetPublicationDate(time.Now()).
+		SetTitle("title").
+		SetAccess("Free").
+		SetGenres("News").
+		SetStockTickers([]string{"A", "B", "C", "D", "E", "F"}).
+		SetStockTickers([]string{"AMAT"})
+	err = invalid.Validate()
+	for _, target := range []error{InvalidValueError, TooManyStockTickersError, InvalidStockTickerError} {
+		if !hasFieldError(err, target) {
+			t.Fatalf("expect %v, got %v", target, err)
+		}
+	}
+}
+
+func TestSitemap_SetNewsMode(t *testing.T) {
+	st := NewSiteMap()
+	st.SetNewsMode(true)
+	st.SetFormat(TxtFormat)
+	now := time.Now()
+	article := func(loc string, published time.Time) *url {
+		u := NewUrl().SetLoc(loc)
+		u.AppendNews(NewNews().SetName("《示例时报》").SetLanguage("zh-cn").SetTitle(loc).SetPublicationDate(published))
+		return u
+	}
+	st.AppendUrl(article("https://www.example.com/old.html", now.Add(-49*time.Hour)))
+	st.AppendUrl(NewUrl().SetLoc("https://www.example.com/about.html"))
+	for i := 0; i < MaxNewsPerSitemap+1; i++ {
+		// 第一篇最早发布，会被去掉
+		st.AppendUrl(article(fmt.Sprintf("https://www.example.com/%d.html", i), now.Add(-time.Hour+time.Duration(i)*time.Second)))
+	}
+	data, err := st.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != MaxNewsPerSitemap {
+		t.Fatalf("expect %d news, got %d", MaxNewsPerSitemap, len(lines))
+	}
+	if lines[0] != "https://www.example.com/1.html" || lines[len(lines)-1] != fmt.Sprintf("https://www.example.com/%d.html", MaxNewsPerSitemap) {
+		t.Fatalf("unexpected news %s ... %s", lines[0], lines[len(lines)-1])
+	}
+	// 原来的网址不受影响
+	if len(st.Token) != MaxNewsPerSitemap+3 {
+		t.Fatalf("news mode changed the sitemap itself")
+	}
+}
+
+func TestSitemap_SetNewsMode_Articles(t *testing.T) {
+	st := NewSiteMap()
+	st.SetNewsMode(true)
+	now := time.Now()
+	article := func(title string, published time.Time) *news {
+		return NewNews().SetName("《示例时报》").SetLanguage("zh-cn").SetTitle(title).SetPublicationDate(published)
+	}
+	// 同一个网址中过期的新闻单独去掉，未过期的保留
+	mixed := NewUrl().SetLoc("https://www.example.com/mixed.html")
+	mixed.AppendNews(article("fresh", now.Add(-time.Hour)))
+	mixed.AppendNews(article("stale", now.Add(-72*time.Hour)))
+	st.AppendUrl(mixed)
+	data, err := st.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<news:title>fresh</news:title>") || strings.Contains(string(data), "stale") {
+		t.Fatalf("unexpected news sitemap %s", data)
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatalf("news mode output is invalid: %v", err)
+	}
+	if len(mixed.Token) != 2 {
+		t.Fatalf("news mode changed the url itself")
+	}
+	if expiry := st.snapshot().newsExpiry(); !expiry.Equal(now.Add(-time.Hour).Truncate(time.Second).Add(MaxNewsAge)) {
+		t.Fatalf("unexpected expiry %s", expiry)
+	}
+
+	// 1000 篇的限制按照新闻计算
+	st = NewSiteMap()
+	st.SetNewsMode(true)
+	old, recent := NewUrl().SetLoc("https://www.example.com/old.html"), NewUrl().SetLoc("https://www.example.com/recent.html")
+	for i := 0; i < 600; i++ {
+		old.AppendNews(article(fmt.Sprintf("old %d", i), now.Add(-2*time.Hour+time.Duration(i)*time.Second)))
+		recent.AppendNews(article(fmt.Sprintf("recent %d", i), now.Add(-time.Hour+time.Duration(i)*time.Second)))
+	}
+	st.AppendUrl(old)
+	st.AppendUrl(recent)
+	snapshot := st.snapshot()
+	count := 0
+	for _, token := range snapshot.Token {
+		count += len(token.(*url).Token)
+	}
+	if len(snapshot.Token) != 2 || count != MaxNewsPerSitemap || len(snapshot.Token[0].(*url).Token) != 400 {
+		t.Fatalf("expect %d news, got %d", MaxNewsPerSitemap, count)
+	}
+	if title := snapshot.Token[0].(*url).Token[0].(*news).Title; title != "old 200" {
+		t.Fatalf("kept the wrong news %s", title)
+	}
+}
+
+func TestHandler_NewsMode(t *testing.T) {
+	st := NewSiteMap()
+	st.SetNewsMode(true)
+	fresh := NewUrl().SetLoc("https://www.douyacun.com/fresh.html")
+	fresh.AppendNews(NewNews().SetName("douyacun").SetLanguage("zh-cn").SetTitle("fresh").SetPublicationDate(time.Now()))
+	st.AppendUrl(fresh)
+	// 一秒之内过期
+	expiring := NewUrl().SetLoc("https://www.douyacun.com/expiring.html")
+	expiring.AppendNews(NewNews().SetName("douyacun").SetLanguage("zh-cn").SetTitle("expiring").SetPublicationDate(time.Now().Add(-MaxNewsAge + time.Second)))
+	st.AppendUrl(expiring)
+
+	handler := NewHandler(st)
+	get := func() string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/sitemap.xml", nil))
+		return w.Body.String()
+	}
+	if body := get(); !strings.Contains(body, "expiring.html") {
+		t.Fatalf("unexpected sitemap %s", body)
+	}
+	time.Sleep(time.Until(handler.expires) + 10*time.Millisecond)
+	// 网址没有变化，但缓存在新闻过期后失效
+	if body := get(); strings.Contains(body, "expiring.html") || !strings.Contains(body, "fresh.html") {
+		t.Fatalf("expired news still served %s", body)
+	}
+}
@@ -0,0 +1,235 @@
+package gositemap
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 滚动更新的新闻sitemap，适合长期运行的服务
+// Add 添加新发布的新闻，Run 在新闻变化时以及每隔 interval 重新生成，去掉超过 48 小时的新闻
+// 生成的sitemap通过 Storage 写入，设置了 SetIndex 时同时更新 sitemapindex 中对应的条目
+type newsGenerator struct {
+	*options
+	mu       sync.Mutex
+	articles []*url
+	changed  chan struct{}
+	interval time.Duration
+	index    string
+	onError  func(error)
+	now      func() time.Time
+	// 上次生成之后新闻是否有变化，没有变化时 Flush 不重写
+	dirty    bool
+	filename string
+	// 上次生成之后 Add 的网址，生成后只向搜索引擎提交这些网址
+	added map[string]bool
+}
+
+// 默认文件名为 sitemap-news.xml
+func NewNewsGenerator() *newsGenerator {
+	o := NewOptions()
+	o.SetFilename("sitemap-news.xml")
+	return &newsGenerator{
+		options:  o,
+		changed:  make(chan struct{}, 1),
+		interval: 10 * time.Minute,
+		now:      time.Now,
+		dirty:    true,
+	}
+}
+
+// 定时重新生成的间隔，默认 10 分钟
+func (g *newsGenerator) SetInterval(interval time.Duration) *newsGenerator {
+	if interval > 0 {
+		g.interval = interval
+	}
+	return g
+}
+
+// 每次生成后更新 storage 中 filename 这个 sitemapindex 里新闻sitemap的条目，其他条目保持不变
+func (g *newsGenerator) SetIndex(filename string) *newsGenerator {
+	g.index = filename
+	return g
+}
+
+// 设置后 Run 中生成失败时调用 fn 并继续运行，否则 Run 返回错误
+func (g *newsGenerator) SetErrorHandler(fn func(error)) *newsGenerator {
+	g.onError = fn
+	return g
+}
+
+// 添加新发布的新闻，u 需要通过 AppendNews 包含新闻，相同网址的新闻会被替换
+// 发布时间超过 48 小时的新闻返回 NewsExpiredError
+func (g *newsGenerator) Add(u *url) error {
+	if !strings.HasPrefix(u.Loc, "http") {
+		u.Loc = g.absUrl(u.Loc)
+	}
+	if loc := g.canonical.apply(u.Loc); loc != u.Loc {
+		canonical := *u
+		canonical.Loc = loc
+		u = &canonical
+	}
+	published, err := publishedAt(u)
+	if err != nil {
+		return err
+	}
+	if g.now().Sub(published) > MaxNewsAge {
+		return &FieldError{Loc: u.Loc, Field: "news:publication_date", Value: published.Format(time.RFC3339), Err: NewsExpiredError}
+	}
+	g.mu.Lock()
+	replaced := false
+	for i, article := range g.articles {
+		if article.Loc == u.Loc {
+			g.articles[i], replaced = u, true
+			break
+		}
+	}
+	if !replaced {
+		g.articles = append(g.articles, u)
+	}
+	if g.added == nil {
+		g.added = make(map[string]bool)
+	}
+	g.added[u.Loc] = true
+	g.dirty = true
+	g.mu.Unlock()
+	select {
+	case g.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// 当前窗口内的新闻数量
+func (g *newsGenerator) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.articles)
+}
+
+// 先生成一次，之后在 Add 之后以及每隔 interval 重新生成，直到 ctx 结束
+func (g *newsGenerator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := g.flush(ctx); err != nil {
+			if g.onError == nil {
+				return err
+			}
+			g.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// 去掉过期的新闻，写入新闻sitemap并更新 sitemapindex
+// 与上次生成相比没有新增或过期的新闻时不重写，也不通知搜索引擎，重写后只提交上次生成之后 Add 的网址
+func (g *newsGenerator) Flush() (filename string, err error) {
+	return g.flush(context.Background())
+}
+
+// Run 中 ctx 取消时同时停止通知搜索引擎
+func (g *newsGenerator) flush(ctx context.Context) (filename string, err error) {
+	now := g.now()
+	o := *g.options
+	o.newsMode = true
+	o.notifier = nil
+	st := &sitemap{options: &o, urlSet: &urlSet{base: &base{}}}
+
+	var changed []string
+	g.mu.Lock()
+	added := g.added
+	g.added = nil
+	kept := g.articles[:0]
+	for _, article := range g.articles {
+		if published, e := publishedAt(article); e == nil && now.Sub(published) <= MaxNewsAge {
+			kept = append(kept, article)
+			st.AppendUrl(article)
+			if added[article.Loc] {
+				changed = append(changed, article.Loc)
+			}
+		}
+	}
+	for i := len(kept); i < len(g.articles); i++ {
+		g.articles[i] = nil
+	}
+	if len(kept) < len(g.articles) {
+		g.dirty = true
+	}
+	g.articles = kept
+	dirty := g.dirty
+	g.dirty = false
+	g.mu.Unlock()
+
+	if !dirty {
+		return g.filename, nil
+	}
+	defer func() {
+		g.mu.Lock()
+		if err != nil {
+			// 下次生成时重新提交
+			g.dirty = true
+			for loc := range added {
+				if g.added == nil {
+					g.added = make(map[string]bool)
+				}
+				g.added[loc] = true
+			}
+		} else {
+			g.filename = filename
+		}
+		g.mu.Unlock()
+	}()
+	if filename, err = st.Storage(); err != nil {
+		return
+	}
+	if g.index != "" {
+		if err = g.updateIndex(g.absUrl(filename), st.lastPublished()); err != nil {
+			return
+		}
+	}
+	st.notifier = g.notifier
+	err = st.notify(ctx, filename, changed)
+	return
+}
+
+// 更新 sitemapindex 中新闻sitemap的条目，内容没有变化时不重写
+func (g *newsGenerator) updateIndex(loc string, lastmod time.Time) error {
+	storage := g.store()
+	index := NewSiteMapIndex()
+	current, err := readFile(storage, g.index)
+	if err == nil {
+		if index, err = ParseSiteMapIndex(bytes.NewReader(current)); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	index.Update(loc, lastmod)
+	data, err := index.ToXml()
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, data) {
+		return nil
+	}
+	return writeFile(storage, g.index, data, false)
+}
+
+// 新闻中最新的发布时间，没有新闻时为零值
+func (u *urlSet) lastPublished() (newest time.Time) {
+	for _, token := range u.Token {
+		if t, err := publishedAt(token.(*url)); err == nil && t.After(newest) {
+			newest = t
+		}
+	}
+	return
+}
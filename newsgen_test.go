@@ -0,0 +1,194 @@
+package gositemap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newArticle(loc string, published time.Time) *url {
+	u := NewUrl().SetLoc(loc)
+	u.AppendNews(NewNews().
+		SetName("douyacun").
+		SetLanguage("zh-cn").
+		SetPublicationDate(published).
+		SetTitle(loc))
+	return u
+}
+
+func TestNewsGenerator_Flush(t *testing.T) {
+	storage := NewMemoryStorage()
+	index := NewSiteMapIndex()
+	index.Append("https://www.douyacun.com/sitemap-pages.xml")
+	if err := index.Save(storage, "sitemap-index.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	gen := NewNewsGenerator()
+	gen.SetDefaultHost("https://www.douyacun.com")
+	gen.SetStorage(storage)
+	gen.SetIndex("sitemap-index.xml")
+
+	if err := gen.Add(NewUrl().SetLoc("/page.html")); !errors.Is(err, MissingFieldError) {
+		t.Fatalf("expect MissingFieldError, got %v", err)
+	}
+	if err := gen.Add(newArticle("/old.html", now.Add(-49*time.Hour))); !errors.Is(err, NewsExpiredError) {
+		t.Fatalf("expect NewsExpiredError, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := gen.Add(newArticle(fmt.Sprintf("/news/%d.html", i), now.Add(-time.Duration(46-i)*time.Hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 相同网址替换原来的新闻
+	if err := gen.Add(newArticle("/news/2.html", now.Add(-time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if gen.Len() != 3 {
+		t.Fatalf("expect 3 articles, got %d", gen.Len())
+	}
+
+	filename, err := gen.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != "sitemap-news.xml" {
+		t.Fatalf("unexpected filename %s", filename)
+	}
+	data, err := readFile(storage, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(data, []byte("<news:news>")) != 3 {
+		t.Fatalf("unexpected news sitemap %s", data)
+	}
+	indexData, err := readFile(storage, "sitemap-index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSiteMapIndex(bytes.NewReader(indexData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.SiteMap) != 2 || parsed.SiteMap[1].Loc != "https://www.douyacun.com/sitemap-news.xml" ||
+		parsed.SiteMap[1].LastMod != now.Add(-time.Hour).Format(time.RFC3339) {
+		t.Fatalf("unexpected sitemapindex %s", indexData)
+	}
+
+	// 新闻没有变化时不重写
+	if err = storage.Delete(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gen.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = readFile(storage, filename); !os.IsNotExist(err) {
+		t.Fatalf("expect no rewrite, got %v", err)
+	}
+
+	// 三个小时后，前两篇超过 48 小时
+	gen.now = func() time.Time { return now.Add(3 * time.Hour) }
+	if _, err = gen.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if gen.Len() != 1 {
+		t.Fatalf("expect 1 article, got %d", gen.Len())
+	}
+	if data, err = readFile(storage, filename); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(data, []byte("<news:news>")) != 1 {
+		t.Fatalf("unexpected news sitemap %s", data)
+	}
+	if indexData, err = readFile(storage, "sitemap-index.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(indexData), "sitemap-news.xml") != 1 {
+		t.Fatalf("unexpected sitemapindex %s", indexData)
+	}
+}
+
+func TestNewsGenerator_FlushNotify(t *testing.T) {
+	var urls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			UrlList []string `json:"urlList"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		urls = append(urls, body.UrlList...)
+	}))
+	defer server.Close()
+
+	key, err := GenerateIndexNowKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	gen := NewNewsGenerator()
+	gen.SetDefaultHost("https://www.douyacun.com")
+	gen.SetStorage(NewMemoryStorage())
+	gen.SetNotifier(NewNotifier().SetIndexNow(key, "").SetIndexNowEndpoint(server.URL))
+	for _, loc := range []string{"/news/1.html", "/news/2.html"} {
+		if err = gen.Add(newArticle(loc, now.Add(-time.Hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = gen.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// 只提交上次生成之后新增的新闻
+	if err = gen.Add(newArticle("/news/3.html", now)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gen.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gen.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "https://www.douyacun.com/news/1.html https://www.douyacun.com/news/2.html https://www.douyacun.com/news/3.html"
+	if strings.Join(urls, " ") != want {
+		t.Fatalf("unexpected urls %v", urls)
+	}
+}
+
+func TestNewsGenerator_Run(t *testing.T) {
+	storage := NewMemoryStorage()
+	gen := NewNewsGenerator()
+	gen.SetStorage(storage)
+	gen.SetInterval(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- gen.Run(ctx)
+	}()
+	if err := gen.Add(newArticle("https://www.douyacun.com/news/1.html", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	// Add 之后重新生成，不需要等待 interval
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := readFile(storage, "sitemap-news.xml")
+		if err == nil && bytes.Contains(data, []byte("news/1.html")) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("news sitemap not updated: %s %v", data, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+}
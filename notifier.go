@@ -0,0 +1,260 @@
+package gositemap
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"time"
+)
+
+const (
+	// IndexNowEndpoint defines default endpoint shared by search engines supporting IndexNow
+	IndexNowEndpoint = "https://api.indexnow.org/indexnow"
+	// MaxIndexNowUrls defines max urls per IndexNow request
+	MaxIndexNowUrls = 10000
+	// DefaultNotifyTimeout defines default timeout of notifying search engines after Storage
+	DefaultNotifyTimeout = time.Minute
+)
+
+var (
+	IndexNowKeyError = errors.New("IndexNow key 只能包含字母、数字和-，长度为8到128个字符")
+)
+
+// 发布sitemap后通知搜索引擎
+// Ping 将sitemap地址提交到 ping 地址，Submit 按照 IndexNow 协议提交变化的网址
+type notifier struct {
+	client      *http.Client
+	pings       []string
+	endpoint    string
+	key         string
+	keyLocation string
+	retries     int
+	backoff     time.Duration
+	timeout     time.Duration
+	submitAll   bool
+	baidu       *baiduPush
+}
+
+func NewNotifier() *notifier {
+	return &notifier{
+		client:   http.DefaultClient,
+		endpoint: IndexNowEndpoint,
+		retries:  3,
+		backoff:  time.Second,
+		timeout:  DefaultNotifyTimeout,
+	}
+}
+
+func (n *notifier) SetClient(client *http.Client) *notifier {
+	n.client = client
+	return n
+}
+
+// 添加 ping 地址，sitemap地址编码后拼接在最后，如 https://www.example.com/ping?sitemap=
+func (n *notifier) AddPing(endpoint string) *notifier {
+	n.pings = append(n.pings, endpoint)
+	return n
+}
+
+// 开启 IndexNow，keyLocation 为空时搜索引擎从 https://host/{key}.txt 获取 key
+func (n *notifier) SetIndexNow(key, keyLocation string) *notifier {
+	n.key = key
+	n.keyLocation = keyLocation
+	return n
+}
+
+// IndexNow 提交地址，默认为 https://api.indexnow.org/indexnow
+func (n *notifier) SetIndexNowEndpoint(endpoint string) *notifier {
+	n.endpoint = endpoint
+	return n
+}
+
+// Submit 时同时推送到百度
+func (n *notifier) SetBaiduPush(push *baiduPush) *notifier {
+	n.baidu = push
+	return n
+}
+
+// 请求失败或者返回 429、5xx 时最多重试 retries 次，第 i 次重试前等待 backoff * 2^(i-1)
+func (n *notifier) SetRetries(retries int, backoff time.Duration) *notifier {
+	if retries >= 0 {
+		n.retries = retries
+	}
+	n.backoff = backoff
+	return n
+}
+
+// sitemap Storage 之后通知搜索引擎总共的超时时间，包括所有重试，默认 1 分钟，0 表示不限制
+func (n *notifier) SetTimeout(timeout time.Duration) *notifier {
+	if timeout >= 0 {
+		n.timeout = timeout
+	}
+	return n
+}
+
+// 没有开启增量生成时，sitemap Storage 之后是否提交全部网址，默认只 ping
+// 每次都会提交整个站点，注意百度推送每天的配额
+func (n *notifier) SetSubmitAll(submitAll bool) *notifier {
+	n.submitAll = submitAll
+	return n
+}
+
+// 加上 timeout 限制的 ctx
+func (n *notifier) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if n.timeout > 0 {
+		return context.WithTimeout(ctx, n.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// 生成随机的 IndexNow key，32个十六进制字符
+func GenerateIndexNowKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// 将 key 文件 {key}.txt 写入 storage，需要放在站点根目录或者 keyLocation 指定的位置
+func (n *notifier) WriteKeyFile(storage Storage) error {
+	if !validIndexNowKey(n.key) {
+		return IndexNowKeyError
+	}
+	return writeFile(storage, n.key+".txt", []byte(n.key), false)
+}
+
+// 将sitemap地址提交到所有 ping 地址，返回第一个错误
+func (n *notifier) Ping(ctx context.Context, sitemapUrl string) error {
+	var first error
+	for _, endpoint := range n.pings {
+		err := n.do(ctx, func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, endpoint+neturl.QueryEscape(sitemapUrl), nil)
+		})
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// 按照 IndexNow 协议提交网址，设置了百度推送时同时推送到百度
+func (n *notifier) Submit(ctx context.Context, urls []string) error {
+	if n.key != "" {
+		if err := n.indexNow(ctx, urls); err != nil {
+			return err
+		}
+	}
+	if n.baidu != nil && len(urls) > 0 {
+		if _, err := n.baidu.Push(ctx, urls); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 同一次请求中的网址必须属于同一个域名，按照域名分组，每次最多提交 10,000 个网址
+func (n *notifier) indexNow(ctx context.Context, urls []string) error {
+	if !validIndexNowKey(n.key) {
+		return IndexNowKeyError
+	}
+	var (
+		hosts  []string
+		groups = make(map[string][]string)
+	)
+	for _, loc := range urls {
+		u, err := neturl.Parse(loc)
+		if err != nil || u.Host == "" {
+			return &FieldError{Loc: loc, Field: "loc", Value: loc, Err: InvalidLocError}
+		}
+		if _, ok := groups[u.Host]; !ok {
+			hosts = append(hosts, u.Host)
+		}
+		groups[u.Host] = append(groups[u.Host], loc)
+	}
+	for _, host := range hosts {
+		list := groups[host]
+		for len(list) > 0 {
+			batch := list
+			if len(batch) > MaxIndexNowUrls {
+				batch = batch[:MaxIndexNowUrls]
+			}
+			list = list[len(batch):]
+			body, err := json.Marshal(struct {
+				Host        string   `json:"host"`
+				Key         string   `json:"key"`
+				KeyLocation string   `json:"keyLocation,omitempty"`
+				UrlList     []string `json:"urlList"`
+			}{host, n.key, n.keyLocation, batch})
+			if err != nil {
+				return err
+			}
+			err = n.do(ctx, func() (*http.Request, error) {
+				req, err := http.NewRequest(http.MethodPost, n.endpoint, bytes.NewReader(body))
+				if err == nil {
+					req.Header.Set("Content-Type", "application/json; charset=utf-8")
+				}
+				return req, err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 发送请求，失败时重试
+func (n *notifier) do(ctx context.Context, newRequest func() (*http.Request, error)) error {
+	var err error
+	for attempt := 0; attempt <= n.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.backoff << uint(attempt-1)):
+			}
+		}
+		var req *http.Request
+		if req, err = newRequest(); err != nil {
+			return err
+		}
+		var resp *http.Response
+		if resp, err = n.client.Do(req.WithContext(ctx)); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return err
+		}
+	}
+	return err
+}
+
+func validIndexNowKey(key string) bool {
+	if len(key) < 8 || len(key) > 128 {
+		return false
+	}
+	for _, r := range key {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+package gositemap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotifier_Submit(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		batches  [][]string
+		attempts int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// 第一次请求返回 429，需要重试
+		if attempts == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var body struct {
+			Host    string   `json:"host"`
+			Key     string   `json:"key"`
+			UrlList []string `json:"urlList"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Key != "0123456789abcdef" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		for _, u := range body.UrlList {
+			if !strings.Contains(u, "://"+body.Host+"/") {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		batches = append(batches, body.UrlList)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	var urls []string
+	for i := 0; i < MaxIndexNowUrls+1; i++ {
+		urls = append(urls, fmt.Sprintf("https://www.douyacun.com/%d", i))
+	}
+	urls = append(urls, "https://m.douyacun.com/")
+	n := NewNotifier().
+		SetIndexNow("0123456789abcdef", "").
+		SetIndexNowEndpoint(server.URL).
+		SetRetries(2, time.Millisecond)
+	if err := n.Submit(context.Background(), urls); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 3 || len(batches[0]) != MaxIndexNowUrls || len(batches[1]) != 1 || batches[2][0] != "https://m.douyacun.com/" {
+		t.Fatalf("unexpected batches %d", len(batches))
+	}
+
+	// key 不正确时不重试
+	attempts = 0
+	err := NewNotifier().SetIndexNow("wrong-key-0000", "").SetIndexNowEndpoint(server.URL).SetRetries(2, time.Millisecond).
+		Submit(context.Background(), urls[:1])
+	if err == nil || attempts != 2 {
+		t.Fatalf("expected error after 2 attempts, got %v %d", err, attempts)
+	}
+}
+
+func TestSitemap_StorageNotify(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		pings []string
+		urls  []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/ping":
+			pings = append(pings, r.URL.Query().Get("sitemap"))
+		case "/indexnow":
+			var body struct {
+				UrlList []string `json:"urlList"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			urls = append(urls, body.UrlList...)
+		}
+	}))
+	defer server.Close()
+
+	key, err := GenerateIndexNowKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := NewMemoryStorage()
+	n := NewNotifier().
+		AddPing(server.URL+"/ping?sitemap=").
+		SetIndexNow(key, "").
+		SetIndexNowEndpoint(server.URL + "/indexnow")
+	if err = n.WriteKeyFile(storage); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := readFile(storage, key+".txt"); string(data) != key {
+		t.Fatalf("unexpected key file %q", data)
+	}
+
+	build := func(locs ...string) error {
+		st := NewSiteMap()
+		st.SetDefaultHost("https://www.douyacun.com")
+		st.SetStorage(storage)
+		st.SetMaxLinks(2)
+		st.SetIncremental(true)
+		st.SetNotifier(n)
+		for _, loc := range locs {
+			st.AppendUrl(NewUrl().SetLoc(loc))
+		}
+		_, err := st.Storage()
+		return err
+	}
+	if err = build("/a", "/b", "/c"); err != nil {
+		t.Fatal(err)
+	}
+	// 增量生成时只提交重写的分片中的网址
+	if err = build("/a", "/b", "/c", "/d"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pings, " ") != "https://www.douyacun.com/sitemap.xml https://www.douyacun.com/sitemap.xml" {
+		t.Fatalf("unexpected pings %v", pings)
+	}
+	want := "https://www.douyacun.com/a https://www.douyacun.com/b https://www.douyacun.com/c https://www.douyacun.com/c https://www.douyacun.com/d"
+	if strings.Join(urls, " ") != want {
+		t.Fatalf("unexpected urls %v", urls)
+	}
+	// 没有变化时不发送任何请求
+	if err = build("/a", "/b", "/c", "/d"); err != nil {
+		t.Fatal(err)
+	}
+	if len(pings) != 2 || strings.Join(urls, " ") != want {
+		t.Fatalf("unexpected notification %v %v", pings, urls)
+	}
+}
+
+func TestSitemap_StorageSubmitAll(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.URL.Path)
+	}))
+	defer server.Close()
+
+	key, err := GenerateIndexNowKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNotifier().
+		AddPing(server.URL+"/ping?sitemap=").
+		SetIndexNow(key, "").
+		SetIndexNowEndpoint(server.URL + "/indexnow")
+	st := NewSiteMap()
+	st.SetStorage(NewMemoryStorage())
+	st.SetNotifier(n)
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/"))
+	// 没有开启增量生成时不知道哪些网址变化，只 ping
+	if _, err = st.Storage(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(requests, " ") != "/ping" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+	n.SetSubmitAll(true)
+	if _, err = st.Storage(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(requests, " ") != "/ping /ping /indexnow" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestSitemap_StorageNotifyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	storage := NewMemoryStorage()
+	st := NewSiteMap()
+	st.SetStorage(storage)
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/"))
+	// 重试总共需要等待 31 秒，超时后停止
+	st.SetNotifier(NewNotifier().
+		AddPing(server.URL+"/ping?sitemap=").
+		SetRetries(5, time.Second).
+		SetTimeout(100 * time.Millisecond))
+	begin := time.Now()
+	filename, err := st.Storage()
+	if err != context.DeadlineExceeded || time.Since(begin) > 5*time.Second {
+		t.Fatalf("expect context.DeadlineExceeded, got %v after %v", err, time.Since(begin))
+	}
+	if !exists(storage, filename) {
+		t.Fatalf("sitemap not written")
+	}
+
+	// 调用方取消
+	st.notifier.SetTimeout(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err = st.StorageContext(ctx); err != context.Canceled {
+		t.Fatalf("expect context.Canceled, got %v", err)
+	}
+}
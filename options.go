@@ -1,13 +1,17 @@
 package gositemap
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"strings"
 )
 
 const (
 	// MaxSitemapLinks defines max links per sitemap
 	MaxSitemapLinks = 50000
+	// MaxSitemapBytes defines max uncompressed bytes per sitemap
+	MaxSitemapBytes = 50 * 1024 * 1024
 )
 
 type options struct {
@@ -17,6 +21,16 @@ type options struct {
 	compress    bool
 	pretty      bool
 	maxLinks    int
+	maxBytes    int
+	incremental bool
+	storage     Storage
+	notifier    *notifier
+	profile     Profile
+	format      Format
+	title       string
+	order       Order
+	newsMode    bool
+	canonical   Canonical
 }
 
 func NewOptions() *options {
@@ -28,6 +42,7 @@ func NewOptions() *options {
 		compress:    false,
 		pretty:      false,
 		maxLinks:    MaxSitemapLinks,
+		maxBytes:    MaxSitemapBytes,
 	}
 }
 
@@ -59,3 +74,104 @@ func (o *options) SetMaxLinks(max int) {
 		o.maxLinks = max
 	}
 }
+
+// 单个sitemap文件未压缩时的最大字节数，不能超过 50MB，BaiduProfile 下不能超过 10MB
+func (o *options) SetMaxBytes(max int) {
+	if max < o.profile.maxBytes() && max > 0 {
+		o.maxBytes = max
+	}
+}
+
+// 输出规范，默认为 sitemaps.org 协议
+// BaiduProfile 下单个文件不超过 10MB，<mobile:mobile> 使用百度的命名空间
+func (o *options) SetProfile(profile Profile) {
+	o.profile = profile
+	if o.maxBytes > profile.maxBytes() {
+		o.maxBytes = profile.maxBytes()
+	}
+}
+
+// 文件格式，默认为 xml，拆分后的分片使用相同的格式，sitemapindex 总是 xml
+func (o *options) SetFormat(format Format) {
+	o.format = format
+}
+
+// RSS、Atom 订阅源的标题，默认为 defaultHost
+func (o *options) SetFeedTitle(title string) {
+	o.title = title
+}
+
+// 输出时网址的顺序，默认为添加的顺序
+// 多个 goroutine 同时 AppendUrl 时使用 LocOrder 或 LastModOrder 得到确定的输出
+func (o *options) SetOrder(order Order) {
+	o.order = order
+}
+
+// 新闻sitemap，输出时逐篇去掉发布超过 48 小时的新闻，再去掉不包含新闻的网址，超过 1000 篇时只保留最新的 1000 篇
+// HTTP handler 的缓存在最早的一篇新闻过期时失效，过期的新闻不会继续输出
+func (o *options) SetNewsMode(news bool) {
+	o.newsMode = news
+}
+
+// 网址规范化的规则，如 SetCanonical(CanonicalAll)，默认不修改网址也不合并
+// 设置后 AppendUrl 合并规范化之后 loc 相同的网址，lastmod 取较新的一个，图片和视频取并集
+// 设置之前添加的网址在下一次 AppendUrl 或者输出时规范化并合并
+func (o *options) SetCanonical(canonical Canonical) {
+	o.canonical = canonical
+}
+
+// 增量生成，在 publicPath 下保存每个分片的网址和内容摘要，再次生成时只重写发生变化的分片
+// 开启后总是以 filename 生成 sitemapindex
+func (o *options) SetIncremental(incremental bool) {
+	o.incremental = incremental
+}
+
+// sitemap 文件的存储，默认为 publicPath 下的本地文件
+func (o *options) SetStorage(storage Storage) {
+	o.storage = storage
+}
+
+func (o *options) store() Storage {
+	if o.storage != nil {
+		return o.storage
+	}
+	return NewFileStorage(o.publicPath)
+}
+
+// Storage 写入完成后通知搜索引擎
+func (o *options) SetNotifier(notifier *notifier) {
+	o.notifier = notifier
+}
+
+// 文件名去掉扩展名，sitemap.xml => sitemap
+func (o *options) basename() string {
+	return strings.TrimSuffix(o.filename, path.Ext(o.filename))
+}
+
+// 拆分后第i个sitemap的文件名，sitemap-1.xml、sitemap-2.xml ...，扩展名由 format 决定
+func (o *options) shardFilename(i int) string {
+	return fmt.Sprintf("%s-%d%s", o.basename(), i, o.format.ext())
+}
+
+// 以defaultHost补全网址
+func (o *options) absUrl(loc string) string {
+	return strings.TrimRight(o.defaultHost, "/") + "/" + strings.TrimLeft(loc, "/")
+}
+
+// 按照 format 输出的文件名，sitemap.xml、sitemap.txt ...
+func (o *options) formatFilename() string {
+	return o.basename() + o.format.ext()
+}
+
+// 实际写入的文件名，压缩时为 sitemap.xml.gz
+func (o *options) outputFilename() string {
+	if o.compress {
+		return o.formatFilename() + ".gz"
+	}
+	return o.formatFilename()
+}
+
+// 增量生成时保存状态的文件名，sitemap.manifest.json
+func (o *options) manifestFilename() string {
+	return o.basename() + ".manifest.json"
+}
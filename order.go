@@ -0,0 +1,90 @@
+package gositemap
+
+import (
+	"encoding/xml"
+	"sort"
+	"time"
+)
+
+// 输出时网址的顺序
+type Order int
+
+const (
+	// 按照 AppendUrl 的顺序，多个 goroutine 同时添加时顺序不确定
+	InsertionOrder Order = iota
+	// 按照 loc 升序
+	LocOrder
+	// 按照 lastmod 从新到旧，没有 lastmod 的网址排在最后，lastmod 相同时按照 loc 升序
+	LastModOrder
+)
+
+func (o Order) sort(tokens []xml.Token) {
+	switch o {
+	case LocOrder:
+		sort.SliceStable(tokens, func(i, j int) bool {
+			return tokens[i].(*url).Loc < tokens[j].(*url).Loc
+		})
+	case LastModOrder:
+		s := byLastMod{tokens: tokens, times: make([]time.Time, len(tokens))}
+		for i, token := range tokens {
+			s.times[i], _ = parseW3CDate(token.(*url).LastMod)
+		}
+		sort.Stable(s)
+	}
+}
+
+type byLastMod struct {
+	tokens []xml.Token
+	times  []time.Time
+}
+
+func (s byLastMod) Len() int {
+	return len(s.tokens)
+}
+
+func (s byLastMod) Less(i, j int) bool {
+	if !s.times[i].Equal(s.times[j]) {
+		return s.times[i].After(s.times[j])
+	}
+	return s.tokens[i].(*url).Loc < s.tokens[j].(*url).Loc
+}
+
+func (s byLastMod) Swap(i, j int) {
+	s.tokens[i], s.tokens[j] = s.tokens[j], s.tokens[i]
+	s.times[i], s.times[j] = s.times[j], s.times[i]
+}
+
+// 当前网址的副本，按照 order 排序，渲染、拆分、写入都在副本上进行，不会阻塞 AppendUrl
+// 新闻sitemap在副本中去掉过期的新闻，见 SetNewsMode
+// 副本不会再被修改，对副本调用 snapshot 时直接返回
+func (s *sitemap) snapshot() *sitemap {
+	if s.frozen {
+		return s
+	}
+	s.mu.Lock()
+	// SetCanonical 之前添加的网址在这里规范化并合并
+	if s.canonical != 0 {
+		s.canonicalize(s.canonical)
+	}
+	set := &urlSet{
+		base:      &base{xmlns: s.xmlns},
+		Token:     make([]xml.Token, len(s.Token)),
+		version:   s.version,
+		frozen:    true,
+		parseErrs: s.parseErrs,
+	}
+	copy(set.Token, s.Token)
+	s.mu.Unlock()
+	if s.newsMode {
+		set.Token = filterNews(set.Token, time.Now())
+	}
+	s.order.sort(set.Token)
+	return &sitemap{urlSet: set, options: s.options}
+}
+
+// 当前的版本号，每次 AppendUrl 时递增
+func (s *sitemap) currentVersion() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
@@ -0,0 +1,111 @@
+package gositemap
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 多个 goroutine 同时添加网址，同时读取输出
+func buildConcurrently(t *testing.T, order Order) []byte {
+	st := NewSiteMap()
+	st.SetMaxLinks(100)
+	st.SetOrder(order)
+	var (
+		wg      sync.WaitGroup
+		done    = make(chan struct{})
+		handler = NewHandler(st)
+		base    = time.Date(2020, 4, 19, 0, 0, 0, 0, time.UTC)
+	)
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				u := NewUrl().SetLoc(fmt.Sprintf("/p%d/%02d.html", p, i))
+				if i%5 != 0 {
+					u.SetLastmod(base.Add(time.Duration(i%7) * time.Hour))
+				}
+				if i%10 == 0 {
+					u.SetMobile(true)
+				}
+				st.AppendUrl(u)
+			}
+		}(p)
+	}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/sitemap.xml", nil))
+				_, _ = st.Split()
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+
+	shards, err := st.Split()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for _, shard := range shards {
+		data, err := shard.ToXml()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(data)
+	}
+	if len(shards) != 4 || st.currentVersion() != 400 {
+		t.Fatalf("unexpected shards %d, version %d", len(shards), st.currentVersion())
+	}
+	return buf.Bytes()
+}
+
+func TestSitemap_ConcurrentAppendUrl(t *testing.T) {
+	for _, order := range []Order{LocOrder, LastModOrder} {
+		first := buildConcurrently(t, order)
+		if second := buildConcurrently(t, order); !bytes.Equal(first, second) {
+			t.Fatalf("order %d: output is not deterministic", order)
+		}
+	}
+}
+
+func TestSitemap_SetOrder(t *testing.T) {
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.AppendUrl(NewUrl().SetLoc("/c.html").SetLastmod(time.Date(2020, 4, 18, 0, 0, 0, 0, time.UTC)))
+	st.AppendUrl(NewUrl().SetLoc("/a.html"))
+	st.AppendUrl(NewUrl().SetLoc("/d.html").SetLastmod(time.Date(2020, 4, 19, 0, 0, 0, 0, time.UTC)))
+	st.AppendUrl(NewUrl().SetLoc("/b.html").SetLastmod(time.Date(2020, 4, 18, 0, 0, 0, 0, time.UTC)))
+	st.SetFormat(TxtFormat)
+
+	for order, want := range map[Order]string{
+		InsertionOrder: "c a d b",
+		LocOrder:       "a b c d",
+		LastModOrder:   "d b c a",
+	} {
+		st.SetOrder(order)
+		data, err := st.Render()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected bytes.Buffer
+		for _, name := range bytes.Fields([]byte(want)) {
+			expected.WriteString("https://www.douyacun.com/" + string(name) + ".html\n")
+		}
+		if !bytes.Equal(data, expected.Bytes()) {
+			t.Fatalf("order %d: unexpected output %q", order, data)
+		}
+	}
+	// 排序不修改原来的顺序
+	if st.Token[0].(*url).Loc != "https://www.douyacun.com/c.html" {
+		t.Fatalf("order changed the sitemap itself")
+	}
+}
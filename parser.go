@@ -0,0 +1,442 @@
+package gositemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	InvalidSiteMapError = errors.New("无法识别的sitemap文件，根节点必须是urlset或sitemapindex")
+)
+
+// 解析 urlset 或 sitemapindex，支持 gzip 压缩的内容
+// 根节点为 urlset 时返回 sitemap，为 sitemapindex 时返回 siteMapIndex
+func Parse(r io.Reader) (*sitemap, *siteMapIndex, error) {
+	data, err := readAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := rootElement(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	switch root.Local {
+	case "urlset":
+		st, err := ParseSiteMap(bytes.NewReader(data))
+		return st, nil, err
+	case "sitemapindex":
+		index, err := ParseSiteMapIndex(bytes.NewReader(data))
+		return nil, index, err
+	}
+	return nil, nil, InvalidSiteMapError
+}
+
+// 读取全部内容，gzip 压缩的内容会被解压
+func readAll(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		return ioutil.ReadAll(gr)
+	}
+	return ioutil.ReadAll(br)
+}
+
+// 解析 sitemap.xml 或 sitemap.xml.gz 文件
+func ParseFile(filename string) (*sitemap, *siteMapIndex, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fd.Close()
+	return Parse(fd)
+}
+
+// 解析 urlset，包括 image、video、news、xhtml:link、mobile 扩展
+// 网址按照文件中的内容原样保存，不会以 defaultHost 补全，urlset 下除 url 以外的元素由 Validate 报告
+func ParseSiteMap(r io.Reader) (*sitemap, error) {
+	d := xml.NewDecoder(r)
+	root, err := nextStartElement(d)
+	if err != nil {
+		return nil, err
+	}
+	if root.Name.Local != "urlset" {
+		return nil, InvalidSiteMapError
+	}
+	st := NewSiteMap()
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "url" {
+				st.parseErrs = append(st.parseErrs, &FieldError{Field: t.Name.Local, Value: t.Name.Space, Err: UnknownElementError})
+				if err = d.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			u := NewUrl()
+			if err = d.DecodeElement(u, &t); err != nil {
+				return nil, err
+			}
+			st.setNs(u.xmlns)
+			st.Token = append(st.Token, u)
+			st.version++
+		case xml.EndElement:
+			return st, nil
+		}
+	}
+}
+
+// 解析 sitemapindex
+func ParseSiteMapIndex(r io.Reader) (*siteMapIndex, error) {
+	var v struct {
+		XMLName xml.Name
+		SiteMap []Map `xml:"sitemap"`
+	}
+	if err := xml.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	if v.XMLName.Local != "sitemapindex" {
+		return nil, InvalidSiteMapError
+	}
+	index := NewSiteMapIndex()
+	for _, m := range v.SiteMap {
+		m.Loc = strings.TrimSpace(m.Loc)
+		m.LastMod = strings.TrimSpace(m.LastMod)
+		index.SiteMap = append(index.SiteMap, m)
+	}
+	return index, nil
+}
+
+// 按文档中的顺序解析 <url> 的子节点
+func (u *url) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if u.base == nil {
+		u.base = &base{}
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err = u.decodeElement(d, t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (u *url) decodeElement(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Space {
+	case ImageNamespace:
+		if start.Name.Local == "image" {
+			var v xmlImage
+			if err := d.DecodeElement(&v, &start); err != nil {
+				return err
+			}
+			u.appendImage(v.image())
+			return nil
+		}
+	case VideoNamespace:
+		if start.Name.Local == "video" {
+			var v xmlVideo
+			if err := d.DecodeElement(&v, &start); err != nil {
+				return err
+			}
+			u.AppendVideo(v.video())
+			return nil
+		}
+	case NewsNamespace:
+		if start.Name.Local == "news" {
+			var v xmlNews
+			if err := d.DecodeElement(&v, &start); err != nil {
+				return err
+			}
+			u.AppendNews(v.news())
+			return nil
+		}
+	case XhtmlNamespace:
+		if start.Name.Local == "link" {
+			var v xmlElement
+			if err := d.DecodeElement(&v, &start); err != nil {
+				return err
+			}
+			if v.attr("rel") == "alternate" {
+				u.AppendAlternate(NewAlternate(v.attr("hreflang"), v.attr("href")))
+			}
+			return nil
+		}
+	case MobileNamespace:
+		if start.Name.Local == "mobile" {
+			u.SetMobile(true)
+		}
+	case BaiduMobileNamespace:
+		if start.Name.Local == "mobile" {
+			var v xmlElement
+			if err := d.DecodeElement(&v, &start); err != nil {
+				return err
+			}
+			u.SetMobileType(MobileType(v.attr("type")))
+			return nil
+		}
+	case SitemapNamespace, "":
+		return u.decodeSitemapElement(d, start)
+	default:
+		// 通过 RegisterNamespace 注册的扩展
+		if ns, ok := lookupNamespace(start.Name.Space); ok {
+			e, err := decodeExtension(d, start)
+			if err != nil {
+				return err
+			}
+			u.AppendExtension(ns.bit, e)
+			return nil
+		}
+	}
+	return d.Skip()
+}
+
+// sitemap 命名空间中的元素
+func (u *url) decodeSitemapElement(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	s = strings.TrimSpace(s)
+	switch start.Name.Local {
+	case "loc":
+		u.Loc = s
+	case "lastmod":
+		u.LastMod = s
+	case "changefreq":
+		u.ChangeFreq = ChangeFreq(s)
+	case "priority":
+		if priority, err := strconv.ParseFloat(s, 64); err != nil {
+			u.addError("priority", s, &InvalidPriorityError{"Valid values range from 0.0 to 1.0"})
+		} else {
+			u.SetPriority(priority)
+		}
+	default:
+		u.addError(start.Name.Local, s, UnknownElementError)
+	}
+	return nil
+}
+
+// 解析时使用的结构，字段只匹配本地名称，不校验命名空间前缀
+type xmlImage struct {
+	Loc         string `xml:"loc"`
+	Caption     string `xml:"caption"`
+	GeoLocation string `xml:"geo_location"`
+	Title       string `xml:"title"`
+	License     string `xml:"license"`
+}
+
+func (v *xmlImage) image() *image {
+	return &image{
+		Loc:         strings.TrimSpace(v.Loc),
+		Caption:     v.Caption,
+		GeoLocation: v.GeoLocation,
+		Title:       v.Title,
+		License:     strings.TrimSpace(v.License),
+	}
+}
+
+type xmlVideo struct {
+	ThumbnailLoc         string        `xml:"thumbnail_loc"`
+	Title                string        `xml:"title"`
+	Description          string        `xml:"description"`
+	ContentLoc           string        `xml:"content_loc"`
+	ContentSegmentLoc    []*xmlElement `xml:"content_segment_loc"`
+	PlayerLoc            *xmlElement   `xml:"player_loc"`
+	Duration             string        `xml:"duration"`
+	ExpirationDate       string        `xml:"expiration_date"`
+	Rating               string        `xml:"rating"`
+	ViewCount            string        `xml:"view_count"`
+	PublicationDate      string        `xml:"publication_date"`
+	FamilyFriendly       string        `xml:"family_friendly"`
+	Restriction          *xmlElement   `xml:"restriction"`
+	Platform             *xmlElement   `xml:"platform"`
+	Price                []*xmlElement `xml:"price"`
+	RequiresSubscription string        `xml:"requires_subscription"`
+	Uploader             *xmlElement   `xml:"uploader"`
+	Live                 string        `xml:"live"`
+	Tag                  []string      `xml:"tag"`
+	Category             string        `xml:"category"`
+	GalleryLoc           *xmlElement   `xml:"gallery_loc"`
+	Id                   *xmlElement   `xml:"id"`
+}
+
+// 数值字段按照字符串解析，无法解析时记录为字段错误，由 Validate 报告
+func (v *xmlVideo) video() *video {
+	_video := &video{
+		ThumbnailLoc:         strings.TrimSpace(v.ThumbnailLoc),
+		Title:                v.Title,
+		Description:          v.Description,
+		ContentLoc:           strings.TrimSpace(v.ContentLoc),
+		ExpirationDate:       strings.TrimSpace(v.ExpirationDate),
+		PublicationDate:      strings.TrimSpace(v.PublicationDate),
+		FamilyFriendly:       strings.TrimSpace(v.FamilyFriendly),
+		RequiresSubscription: strings.TrimSpace(v.RequiresSubscription),
+		Live:                 strings.TrimSpace(v.Live),
+		Tag:                  v.Tag,
+		Category:             v.Category,
+	}
+	if v.PlayerLoc != nil {
+		_video.PlayerLoc = &PlayerLoc{
+			AllowEmbed: v.PlayerLoc.attr("allow_embed"),
+			Content:    strings.TrimSpace(v.PlayerLoc.Content),
+		}
+	}
+	if v.Restriction != nil {
+		_video.Restriction = &Restriction{
+			Relationship: v.Restriction.attr("relationship"),
+			Content:      strings.TrimSpace(v.Restriction.Content),
+		}
+	}
+	if v.Platform != nil {
+		_video.Platform = &Platform{
+			Relationship: v.Platform.attr("relationship"),
+			Content:      strings.Join(strings.Fields(v.Platform.Content), " "),
+		}
+	}
+	if s := strings.TrimSpace(v.Duration); s != "" {
+		if duration, err := strconv.Atoi(s); err != nil {
+			_video.addError("video:duration", s, InvalidDurationError)
+		} else {
+			_video.Duration = duration
+		}
+	}
+	if s := strings.TrimSpace(v.Rating); s != "" {
+		if rating, err := strconv.ParseFloat(s, 64); err != nil {
+			_video.addError("video:rating", s, InvalidRatingError)
+		} else {
+			_video.Rating = rating
+		}
+	}
+	if s := strings.TrimSpace(v.ViewCount); s != "" {
+		if count, err := strconv.Atoi(s); err != nil {
+			_video.addError("video:view_count", s, InvalidValueError)
+		} else {
+			_video.ViewCount = count
+		}
+	}
+	for _, p := range v.Price {
+		price, err := strconv.ParseFloat(strings.TrimSpace(p.Content), 64)
+		if err != nil {
+			_video.addError("video:price", strings.TrimSpace(p.Content), InvalidValueError)
+			continue
+		}
+		_video.Price = append(_video.Price, &Price{
+			Currency:   p.attr("currency"),
+			Type:       p.attr("type"),
+			Resolution: p.attr("resolution"),
+			Content:    price,
+		})
+	}
+	for _, segment := range v.ContentSegmentLoc {
+		var duration int
+		if d := strings.TrimSpace(segment.attr("duration")); d != "" {
+			var err error
+			if duration, err = strconv.Atoi(d); err != nil {
+				_video.addError("video:content_segment_loc", d, InvalidDurationError)
+				continue
+			}
+		}
+		_video.ContentSegmentLoc = append(_video.ContentSegmentLoc, &ContentSegmentLoc{
+			Duration: duration,
+			Content:  strings.TrimSpace(segment.Content),
+		})
+	}
+	if v.GalleryLoc != nil {
+		_video.GalleryLoc = &GalleryLoc{
+			Title:   v.GalleryLoc.attr("title"),
+			Content: strings.TrimSpace(v.GalleryLoc.Content),
+		}
+	}
+	if v.Id != nil {
+		_video.Id = &VideoId{
+			Type:    v.Id.attr("type"),
+			Content: strings.TrimSpace(v.Id.Content),
+		}
+	}
+	if v.Uploader != nil {
+		_video.Uploader = &Uploader{
+			Info:    v.Uploader.attr("info"),
+			Content: strings.TrimSpace(v.Uploader.Content),
+		}
+	}
+	return _video
+}
+
+type xmlNews struct {
+	Name            string `xml:"publication>name"`
+	Language        string `xml:"publication>language"`
+	Access          string `xml:"access"`
+	Genres          string `xml:"genres"`
+	PublicationDate string `xml:"publication_date"`
+	Title           string `xml:"title"`
+	Keywords        string `xml:"keywords"`
+	StockTickers    string `xml:"stock_tickers"`
+}
+
+func (v *xmlNews) news() *news {
+	return &news{
+		Name:            v.Name,
+		Language:        strings.TrimSpace(v.Language),
+		Access:          NewsAccess(strings.TrimSpace(v.Access)),
+		Genres:          strings.TrimSpace(v.Genres),
+		PublicationDate: strings.TrimSpace(v.PublicationDate),
+		Title:           v.Title,
+		Keywords:        strings.TrimSpace(v.Keywords),
+		StockTickers:    strings.TrimSpace(v.StockTickers),
+	}
+}
+
+// 带属性的文本节点
+type xmlElement struct {
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",chardata"`
+}
+
+func (e *xmlElement) attr(name string) string {
+	for _, attr := range e.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func rootElement(r io.Reader) (xml.Name, error) {
+	start, err := nextStartElement(xml.NewDecoder(r))
+	return start.Name, err
+}
+
+func nextStartElement(d *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package gositemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestParseSiteMap(t *testing.T) {
+	st := NewSiteMap()
+	st.SetPretty(true)
+	u := NewUrl().
+		SetLoc("https://www.douyacun.com/show_image").
+		SetLastmod(time.Now()).
+		SetChangefreq(Daily).
+		SetPriority(0.8)
+	u.AppendImage(NewImage().SetLoc("https://www.douyacun.com/image1.jpg").SetTitle("example").SetCaption("图片的说明。"))
+	u.AppendVideo(NewVideo().
+		SetThumbnailLoc("http://www.example.com/thumbs/123.jpg").
+		SetTitle("适合夏季的烧烤排餐").
+		SetDescription("小安教您如何每次都能烤出美味牛排").
+		SetContentLoc("http://streamserver.example.com/video123.mp4").
+		SetPlayerLoc("http://www.example.com/videoplayer.php?video=123", true).
+		SetDuration(600*time.Second).
+		SetRating(4.2).
+		SetRestriction([]string{"IE", "GB"}, true).
+		SetPrice(6.99, "EUR", true, true).
+		SetUploader("GrillyMcGrillerson", "http://www.example.com/users/grillymcgrillerson"))
+	u.AppendNews(NewNews().SetName("《示例时报》").
+		SetTitle("公司 A 和 B 正在进行合并谈判").
+		SetLanguage("zh-cn").
+		SetPublicationDate(time.Now()))
+	st.AppendUrl(u)
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/"))
+	want, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseSiteMap(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.SetPretty(true)
+	got, err := parsed.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Fatalf("round trip mismatch:\nwant %s\ngot  %s", want, got)
+	}
+}
+
+func TestValidateFile_InvalidNumber(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:video="http://www.google.com/schemas/sitemap-video/1.1">
+  <url>
+    <loc>https://www.douyacun.com/video.html</loc>
+    <video:video>
+      <video:thumbnail_loc>https://www.douyacun.com/123.jpg</video:thumbnail_loc>
+      <video:title>title</video:title>
+      <video:description>description</video:description>
+      <video:content_loc>https://www.douyacun.com/123.mp4</video:content_loc>
+      <video:content_segment_loc duration="1m">https://www.douyacun.com/123-1.mp4</video:content_segment_loc>
+      <video:duration>12.5</video:duration>
+      <video:rating>good</video:rating>
+      <video:view_count>1,000</video:view_count>
+      <video:price currency="EUR">free</video:price>
+    </video:video>
+  </url>
+</urlset>`
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := path.Join(dir, "sitemap.xml")
+	if err = ioutil.WriteFile(filename, []byte(urlset), 0666); err != nil {
+		t.Fatal(err)
+	}
+	// 数值错误不会中断解析，每个字段单独报告
+	var errs ValidationErrors
+	if err = ValidateFile(filename); !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := []struct {
+		field string
+		err   error
+	}{
+		{"video:duration", InvalidDurationError},
+		{"video:rating", InvalidRatingError},
+		{"video:view_count", InvalidValueError},
+		{"video:price", InvalidValueError},
+		{"video:content_segment_loc", InvalidDurationError},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if errs[i].Field != w.field || !errors.Is(errs[i], w.err) || errs[i].Loc != "https://www.douyacun.com/video.html" {
+			t.Errorf("error %d: expected %s %v, got %v", i, w.field, w.err, errs[i])
+		}
+	}
+}
+
+// 解析得到的网址在拆分、写入之后仍然保持原样
+func TestParseSiteMap_Verbatim(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/a.html</loc></url>
+  <url><loc>/b.html</loc></url>
+</urlset>`
+	st, err := ParseSiteMap(bytes.NewReader([]byte(urlset)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.SetStorage(NewMemoryStorage())
+	st.SetMaxLinks(1)
+	if _, err = st.Storage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewHandler(st).load(); err != nil {
+		t.Fatal(err)
+	}
+	if loc := st.Token[0].(*url).Loc; loc != "/a.html" {
+		t.Fatalf("parsed loc modified: %s", loc)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	index := NewSiteMapIndex()
+	index.Append("https://www.douyacun.com/sitemap-1.xml.gz")
+	index.Append("https://www.douyacun.com/sitemap-2.xml.gz")
+	data, err := index.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(data)
+	gw.Close()
+	filename := path.Join(dir, "sitemap.xml.gz")
+	if err = ioutil.WriteFile(filename, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	st, parsed, err := ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != nil || parsed == nil {
+		t.Fatalf("expected sitemapindex")
+	}
+	if len(parsed.SiteMap) != 2 || parsed.SiteMap[1].Loc != "https://www.douyacun.com/sitemap-2.xml.gz" {
+		t.Fatalf("unexpected index %+v", parsed.SiteMap)
+	}
+
+	if _, _, err = Parse(bytes.NewReader([]byte("<rss></rss>"))); err != InvalidSiteMapError {
+		t.Fatalf("expected InvalidSiteMapError, got %v", err)
+	}
+}
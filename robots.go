@@ -0,0 +1,110 @@
+package gositemap
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// robots.txt 中适用于当前 User-agent 的规则
+// 按照最长匹配原则判断，长度相同时 Allow 优先，支持 * 和 $
+type robots struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// 解析 robots.txt，优先使用与 agent 匹配的分组，没有时使用 User-agent: *
+func parseRobots(r io.Reader, agent string) *robots {
+	var (
+		matched, wildcard []robotsRule
+		agents            []string
+		inRules           bool
+		hasMatched        bool
+		current           []robotsRule
+	)
+	agent = strings.ToLower(agent)
+	flush := func() {
+		for _, a := range agents {
+			if a == "*" {
+				wildcard = append(wildcard, current...)
+			} else if strings.Contains(agent, a) {
+				matched = append(matched, current...)
+				hasMatched = true
+			}
+		}
+		agents, current = nil, nil
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			if inRules {
+				flush()
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			current = append(current, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: robotsPattern(value),
+			})
+		}
+	}
+	flush()
+	if hasMatched {
+		return &robots{rules: matched}
+	}
+	return &robots{rules: wildcard}
+}
+
+// 是否允许抓取，path 包含查询参数
+func (r *robots) allowed(path string) bool {
+	var (
+		allow  = true
+		length = -1
+	)
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > length || (rule.length == length && rule.allow) {
+			allow, length = rule.allow, rule.length
+		}
+	}
+	return allow
+}
+
+func robotsPattern(value string) *regexp.Regexp {
+	anchored := strings.HasSuffix(value, "$")
+	value = strings.TrimSuffix(value, "$")
+	parts := strings.Split(value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	pattern := "^" + strings.Join(parts, ".*")
+	if anchored {
+		pattern += "$"
+	}
+	return regexp.MustCompile(pattern)
+}
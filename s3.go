@@ -0,0 +1,334 @@
+package gositemap
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 兼容 S3 协议的对象存储，使用 path-style 地址 endpoint/bucket/key，以 AWS Signature Version 4 签名
+// 适用于 AWS S3、MinIO 以及其他兼容 S3 的对象存储
+type s3Storage struct {
+	endpoint  string
+	bucket    string
+	region    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// endpoint 如 https://s3.us-east-1.amazonaws.com 或 http://127.0.0.1:9000
+func NewS3Storage(endpoint, bucket string) *s3Storage {
+	return &s3Storage{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		bucket:   bucket,
+		region:   "us-east-1",
+		client:   http.DefaultClient,
+	}
+}
+
+func (s *s3Storage) SetCredentials(accessKey, secretKey string) *s3Storage {
+	s.accessKey = accessKey
+	s.secretKey = secretKey
+	return s
+}
+
+func (s *s3Storage) SetRegion(region string) *s3Storage {
+	s.region = region
+	return s
+}
+
+// 所有文件名之前加上的前缀，如 sitemaps/
+func (s *s3Storage) SetPrefix(prefix string) *s3Storage {
+	s.prefix = prefix
+	return s
+}
+
+func (s *s3Storage) SetClient(client *http.Client) *s3Storage {
+	s.client = client
+	return s
+}
+
+// 写入内存，Close 时上传
+func (s *s3Storage) Create(name string) (io.WriteCloser, error) {
+	return &s3Object{storage: s, name: name}, nil
+}
+
+func (s *s3Storage) Open(name string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.key(name), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.check(resp, "open", name); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// 对象存储没有重命名，复制后删除原来的对象
+func (s *s3Storage) Rename(oldName, newName string) error {
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", "/"+s.bucket+"/"+escapePath(s.key(oldName)))
+	resp, err := s.do(http.MethodPut, s.key(newName), nil, header, nil)
+	if err != nil {
+		return err
+	}
+	if err = s.check(resp, "rename", oldName); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	// 复制失败时也可能返回200，错误在响应内容中
+	if bytes.Contains(data, []byte("<Error>")) {
+		return fmt.Errorf("s3 rename %s: %s", oldName, data)
+	}
+	return s.Delete(oldName)
+}
+
+func (s *s3Storage) List(prefix string) ([]string, error) {
+	var (
+		names []string
+		token string
+	)
+	for {
+		query := neturl.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.key(prefix))
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.check(resp, "list", prefix); err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, s.prefix))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// S3 删除不存在的对象时也返回成功，这里先检查对象是否存在
+func (s *s3Storage) Delete(name string) error {
+	resp, err := s.do(http.MethodHead, s.key(name), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err = s.check(resp, "remove", name); err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp, err = s.do(http.MethodDelete, s.key(name), nil, nil, nil); err != nil {
+		return err
+	}
+	if err = s.check(resp, "remove", name); err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *s3Storage) key(name string) string {
+	return s.prefix + name
+}
+
+// 状态码不是 2xx 时关闭响应并返回错误，404 返回满足 os.IsNotExist 的错误
+func (s *s3Storage) check(resp *http.Response, op, name string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return fmt.Errorf("s3 %s %s: %s %s", op, name, resp.Status, bytes.TrimSpace(data))
+}
+
+func (s *s3Storage) do(method, key string, query neturl.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := s.endpoint + "/" + s.bucket
+	if key != "" {
+		u += "/" + escapePath(key)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	signV4(req, body, s.accessKey, s.secretKey, s.region, time.Now())
+	return s.client.Do(req)
+}
+
+// 上传到对象存储的文件
+type s3Object struct {
+	bytes.Buffer
+	storage *s3Storage
+	name    string
+}
+
+func (o *s3Object) abort() error {
+	o.Reset()
+	return nil
+}
+
+func (o *s3Object) Close() error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType(o.name))
+	resp, err := o.storage.do(http.MethodPut, o.storage.key(o.name), nil, header, o.Bytes())
+	if err != nil {
+		return err
+	}
+	if err = o.storage.check(resp, "create", o.name); err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// 根据扩展名判断 Content-Type
+func contentType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return "application/gzip"
+	case strings.HasSuffix(name, ".xml"):
+		return "application/xml"
+	case strings.HasSuffix(name, ".json"):
+		return "application/json"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// AWS Signature Version 4，签名 Host 和请求上已经设置的全部头部
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	if req.Header.Get("X-Amz-Date") == "" {
+		req.Header.Set("X-Amz-Date", now.UTC().Format("20060102T150405Z"))
+	}
+	var (
+		amzDate = req.Header.Get("X-Amz-Date")
+		date    = amzDate[:8]
+		scope   = date + "/" + region + "/s3/aws4_request"
+	)
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, s := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// 按照 S3 的规则编码路径，保留 /
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = escapeS3(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(query neturl.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, escapeS3(k)+"="+escapeS3(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// 除 A-Z a-z 0-9 - _ . ~ 之外的字符都需要编码
+func escapeS3(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -2,26 +2,35 @@ package gositemap
 
 import (
 	"bytes"
-	"compress/gzip"
+	"context"
 	"encoding/xml"
 	"errors"
-	"io/ioutil"
-	"os"
 	"path"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
 	TooMuchLinksError = errors.New("单个sitemap文件过多")
+	TooLargeError     = errors.New("单个sitemap文件过大")
 )
 
+// 序列化时只声明用到的命名空间，见 MarshalXML
 type urlSet struct {
 	*base
-	XMLName    xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
-	XMLNSVideo string   `xml:"xmlns:video,attr,omitempty"`
-	XMLNSImage string   `xml:"xmlns:image,attr,omitempty"`
-	XMLNSNews  string   `xml:"xmlns:news,attr,omitempty"`
-	Token      []xml.Token
+	Token []xml.Token
+	// 每次添加网址时递增，用于判断内容是否变化
+	version uint64
+	// 保护 Token、xmlns 和 version，AppendUrl 可以在多个 goroutine 中同时调用
+	mu sync.Mutex
+	// snapshot 生成的副本
+	frozen bool
+	// 规范化之后的 loc 在 Token 中的位置，以及已经加入索引的网址数量，见 merge
+	index   map[string]int
+	indexed int
+	// 解析时 urlset 下无法识别的元素，见 ParseSiteMap
+	parseErrs ValidationErrors
 }
 
 type sitemap struct {
@@ -38,24 +47,30 @@ func NewSiteMap() *sitemap {
 	}
 }
 
+// 可以在多个 goroutine 中同时调用，输出的顺序见 SetOrder
+// 设置了 SetCanonical 时添加规范化 loc 之后的副本，与已有的网址重复时合并到已有的网址中
 func (s *sitemap) AppendUrl(url *url) {
 	if !strings.HasPrefix(url.Loc, "http") {
-		url.Loc = strings.TrimRight(s.defaultHost, "/") + strings.TrimLeft(url.Loc, "/")
+		url.Loc = s.absUrl(url.Loc)
 	}
+	// 在副本上修改 loc，调用方持有的网址以及已经生成的副本不受影响
+	if loc := s.canonical.apply(url.Loc); loc != url.Loc {
+		canonical := *url
+		canonical.Loc = loc
+		url = &canonical
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.setNs(url.xmlns)
+	s.version++
+	if s.canonical != 0 && s.merge(url, s.canonical) {
+		return
+	}
 	s.Token = append(s.Token, url)
 }
 
 func (s *sitemap) ToXml() ([]byte, error) {
-	if ImageXmlNS&s.xmlns == ImageXmlNS {
-		s.urlSet.XMLNSImage = "http://www.google.com/schemas/sitemap-image/1.1"
-	}
-	if VideoXmlNS&s.xmlns == VideoXmlNS {
-		s.urlSet.XMLNSVideo = "http://www.google.com/schemas/sitemap-video/1.1"
-	}
-	if NewsXmlNS&s.xmlns == NewsXmlNS {
-		s.urlSet.XMLNSNews = "http://www.google.com/schemas/sitemap-news/0.9"
-	}
+	s = s.snapshot()
 	if len(s.urlSet.Token) > s.options.maxLinks {
 		return nil, TooMuchLinksError
 	}
@@ -75,48 +90,190 @@ func (s *sitemap) ToXml() ([]byte, error) {
 		return nil, err
 	}
 	buf.Write(data)
+	if buf.Len() > s.options.maxBytes {
+		return nil, TooLargeError
+	}
 	return buf.Bytes(), nil
 }
 
-// filename 生成sitemap文件名
-func (s *sitemap) Storage() (filename string, err error) {
+// 按照 maxLinks 和 maxBytes 拆分成多个sitemap，文件名依次为 sitemap-1.xml、sitemap-2.xml ...
+// 大小按照 format 计算
+func (s *sitemap) Split() ([]*sitemap, error) {
+	s = s.snapshot()
+	overhead, err := s.overhead()
+	if err != nil {
+		return nil, err
+	}
 	var (
-		data []byte
+		shards []*sitemap
+		size   = overhead
+		cur    = s.newShard(1)
 	)
-	data, err = s.ToXml()
+	for _, token := range s.Token {
+		data, err := s.marshalUrl(token.(*url))
+		if err != nil {
+			return nil, err
+		}
+		n := len(data)
+		if len(cur.Token) > 0 && (len(cur.Token) >= s.maxLinks || size+n > s.maxBytes) {
+			shards = append(shards, cur)
+			cur = s.newShard(len(shards) + 1)
+			size = overhead
+		}
+		if size+n > s.maxBytes {
+			return nil, TooLargeError
+		}
+		cur.add(token.(*url))
+		size += n
+	}
+	return append(shards, cur), nil
+}
+
+// 将网址加入分片，不经过 AppendUrl，不再补全和规范化 loc
+// 解析得到的网址保持原样，仍被sitemap引用的网址也不会被修改
+func (u *urlSet) add(url *url) {
+	u.setNs(url.xmlns)
+	u.Token = append(u.Token, url)
+}
+
+// 不包含任何网址时urlset的字节数
+func (s *sitemap) overhead() (int, error) {
+	empty := s.newShard(0)
+	empty.setNs(s.xmlns)
+	data, err := empty.Render()
 	if err != nil {
-		return
+		return 0, err
 	}
-	if err = os.MkdirAll(s.publicPath, 0755); err != nil {
-		return
+	// 有子节点时 </urlset> 前会多一个换行
+	return len(data) + 1, nil
+}
+
+// 网址中最新的 lastmod，没有时为零值
+func (u *urlSet) lastMod() (newest time.Time) {
+	for _, token := range u.Token {
+		if t, err := parseW3CDate(token.(*url).LastMod); err == nil && t.After(newest) {
+			newest = t
+		}
 	}
-	if s.compress {
-		basename := strings.TrimRight(s.filename, path.Ext(s.filename))
-		filename = basename + ".xml.gz"
-		if fd, err := os.OpenFile(path.Join(s.publicPath, filename), os.O_WRONLY|os.O_CREATE, 0666); err != nil {
-			return "", err
-		} else {
-			gw := gzip.NewWriter(fd)
-			if _, err := gw.Write(data); err != nil {
-				return "", err
-			}
-			_ = fd.Close()
-			_ = gw.Close()
-			return filename, nil
+	return
+}
+
+// 第i个分片，继承当前sitemap的配置
+func (s *sitemap) newShard(i int) *sitemap {
+	o := *s.options
+	o.filename = s.shardFilename(i)
+	return &sitemap{
+		options: &o,
+		urlSet: &urlSet{
+			base: &base{},
+		},
+	}
+}
+
+// 单个网址在urlset中序列化后的内容，pretty时包含前面的换行和缩进
+func marshalToken(token xml.Token, pretty bool) ([]byte, error) {
+	if pretty {
+		data, err := xml.MarshalIndent(token, "  ", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte("\n"), data...), nil
+	}
+	return xml.Marshal(token)
+}
+
+// filename 生成sitemap文件名，SetFormat 时扩展名由 format 决定，如 sitemap.txt
+// 超过 maxLinks 或 maxBytes 时自动拆分成 sitemap-1.xml、sitemap-2.xml ...，并以 filename 生成 sitemapindex 文件
+// 开启 SetIncremental 时只重写发生变化的分片，见 storageIncremental
+// 设置了 SetNotifier 时，写入完成后通知搜索引擎，通知失败时返回的 filename 仍然有效
+// 通知最多持续 notifier 的 SetTimeout，需要提前取消时使用 StorageContext
+func (s *sitemap) Storage() (filename string, err error) {
+	return s.StorageContext(context.Background())
+}
+
+// 同 Storage，ctx 取消时停止通知搜索引擎并返回 ctx.Err()，已经写入的文件仍然有效
+// 增量生成时只提交重写的分片中的网址，没有变化时不通知；
+// 否则无法知道哪些网址发生了变化，只 ping，notifier 设置了 SetSubmitAll 时才提交全部网址
+func (s *sitemap) StorageContext(ctx context.Context) (filename string, err error) {
+	s = s.snapshot()
+	var changed []string
+	if s.incremental {
+		var updated bool
+		if filename, changed, updated, err = s.storageIncremental(); err != nil || !updated {
+			return
 		}
 	} else {
-		return s.filename, ioutil.WriteFile(path.Join(s.publicPath, s.filename), data, 0666)
+		if filename, err = s.storageAll(); err != nil {
+			return
+		}
+		if s.notifier != nil && s.notifier.submitAll {
+			for _, token := range s.Token {
+				changed = append(changed, token.(*url).Loc)
+			}
+		}
+	}
+	err = s.notify(ctx, filename, changed)
+	return
+}
+
+// ping sitemap地址并提交变化的网址，没有设置 notifier 时什么也不做
+func (s *sitemap) notify(ctx context.Context, filename string, changed []string) error {
+	if s.notifier == nil {
+		return nil
+	}
+	ctx, cancel := s.notifier.withTimeout(ctx)
+	defer cancel()
+	if err := s.notifier.Ping(ctx, s.absUrl(filename)); err != nil {
+		return err
+	}
+	return s.notifier.Submit(ctx, changed)
+}
+
+// 写入全部分片
+func (s *sitemap) storageAll() (filename string, err error) {
+	var shards []*sitemap
+	if shards, err = s.Split(); err != nil {
+		return
+	}
+	if len(shards) == 1 {
+		return s.write()
+	}
+	index := NewSiteMapIndex()
+	for _, shard := range shards {
+		if filename, err = shard.write(); err != nil {
+			return
+		}
+		index.Append(s.absUrl(filename), shard.lastMod())
+	}
+	filename = s.filename
+	err = index.Save(s.store(), filename)
+	return
+}
+
+// 写入单个sitemap文件
+func (s *sitemap) write() (filename string, err error) {
+	var (
+		data []byte
+	)
+	data, err = s.Render()
+	if err != nil {
+		return
 	}
+	filename = s.outputFilename()
+	err = writeFile(s.store(), filename, data, s.compress)
+	return
 }
 
 type Map struct {
 	XMLName xml.Name `xml:"sitemap"`
 	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
 }
 
 type siteMapIndex struct {
 	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
 	SiteMap []Map
+	version uint64
 }
 
 func NewSiteMapIndex() *siteMapIndex {
@@ -125,11 +282,32 @@ func NewSiteMapIndex() *siteMapIndex {
 	}
 }
 
-func (s *siteMapIndex) Append(loc string) {
+// lastmod 为该sitemap最后修改的时间，可以省略
+func (s *siteMapIndex) Append(loc string, lastmod ...time.Time) {
 	m := Map{
 		Loc: loc,
 	}
+	if len(lastmod) > 0 && !lastmod[0].IsZero() {
+		m.LastMod = lastmod[0].Format(time.RFC3339)
+	}
 	s.SiteMap = append(s.SiteMap, m)
+	s.version++
+}
+
+// 更新 loc 对应的条目，没有时添加到最后
+func (s *siteMapIndex) Update(loc string, lastmod ...time.Time) {
+	for i := range s.SiteMap {
+		if s.SiteMap[i].Loc != loc {
+			continue
+		}
+		s.SiteMap[i].LastMod = ""
+		if len(lastmod) > 0 && !lastmod[0].IsZero() {
+			s.SiteMap[i].LastMod = lastmod[0].Format(time.RFC3339)
+		}
+		s.version++
+		return
+	}
+	s.Append(loc, lastmod...)
 }
 
 func (s *siteMapIndex) ToXml() ([]byte, error) {
@@ -151,11 +329,16 @@ func (s *siteMapIndex) Storage(filepath string) (filename string, err error) {
 	if path.Ext(filepath) != ".xml" {
 		return "", errors.New("建议以.xml作为文件扩展名")
 	}
-	var data []byte
-	if data, err = s.ToXml(); err != nil {
-		return
-	}
-	err = ioutil.WriteFile(filepath, data, 0666)
 	filename = path.Base(filepath)
+	err = s.Save(NewFileStorage(path.Dir(filepath)), filename)
 	return
 }
+
+// 写入 storage 中的 name 文件
+func (s *siteMapIndex) Save(storage Storage, name string) error {
+	data, err := s.ToXml()
+	if err != nil {
+		return err
+	}
+	return writeFile(storage, name, data, false)
+}
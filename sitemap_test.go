@@ -2,7 +2,11 @@ package gositemap
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path"
+	"strings"
 	"testing"
 	"time"
 )
@@ -182,3 +186,88 @@ func TestNewSiteMapIndex(t *testing.T) {
 	}
 	fmt.Printf("%v", filename)
 }
+
+func TestSitemap_Split(t *testing.T) {
+	st := NewSiteMap()
+	st.SetPretty(true)
+	st.SetMaxLinks(3)
+	for i := 0; i < 7; i++ {
+		st.AppendUrl(NewUrl().SetLoc(fmt.Sprintf("https://www.douyacun.com/article%d.html", i)))
+	}
+	shards, err := st.Split()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) != 3 {
+		t.Fatalf("expected 3 shards, got %d", len(shards))
+	}
+	if shards[2].filename != "sitemap-3.xml" || len(shards[2].Token) != 1 {
+		t.Fatalf("unexpected last shard %s with %d links", shards[2].filename, len(shards[2].Token))
+	}
+
+	st = NewSiteMap()
+	st.SetMaxBytes(1024)
+	for i := 0; i < 50; i++ {
+		st.AppendUrl(NewUrl().SetLoc(fmt.Sprintf("https://www.douyacun.com/article%d.html", i)).SetChangefreq(Daily))
+	}
+	if shards, err = st.Split(); err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) < 2 {
+		t.Fatalf("expected split by bytes, got %d shards", len(shards))
+	}
+	for _, shard := range shards {
+		if _, err := shard.ToXml(); err != nil {
+			t.Fatalf("%s: %v", shard.filename, err)
+		}
+	}
+}
+
+func TestSitemap_StorageIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.SetPublicPath(dir)
+	st.SetMaxLinks(2)
+	for i := 0; i < 5; i++ {
+		u := NewUrl().SetLoc(fmt.Sprintf("/article%d.html", i))
+		if i != 4 {
+			u.SetLastmod(time.Date(2020, 4, 10+i, 0, 0, 0, 0, time.UTC))
+		}
+		st.AppendUrl(u)
+	}
+	filename, err := st.Storage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != "sitemap.xml" {
+		t.Fatalf("unexpected index filename %s", filename)
+	}
+	data, err := ioutil.ReadFile(path.Join(dir, filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		loc := fmt.Sprintf("<loc>https://www.douyacun.com/sitemap-%d.xml</loc>", i)
+		if !strings.Contains(string(data), loc) {
+			t.Fatalf("index missing %s:\n%s", loc, data)
+		}
+		if _, err := os.Stat(path.Join(dir, fmt.Sprintf("sitemap-%d.xml", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 分片的 lastmod 为其中网址最新的 lastmod，没有时省略
+	for _, lastmod := range []string{"2020-04-11T00:00:00Z", "2020-04-13T00:00:00Z"} {
+		if !strings.Contains(string(data), "<lastmod>"+lastmod+"</lastmod>") {
+			t.Fatalf("index missing lastmod %s:\n%s", lastmod, data)
+		}
+	}
+	if strings.Count(string(data), "<lastmod>") != 2 {
+		t.Fatalf("unexpected lastmod:\n%s", data)
+	}
+}
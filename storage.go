@@ -0,0 +1,261 @@
+package gositemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// sitemap 文件的存储，文件名为相对于存储根目录的路径
+// 默认使用 publicPath 下的本地文件，可以通过 SetStorage 替换为内存或对象存储
+type Storage interface {
+	// 创建或覆盖文件，Close 之后写入的内容才完整可见
+	Create(name string) (io.WriteCloser, error)
+	// 文件不存在时返回的错误满足 os.IsNotExist
+	Open(name string) (io.ReadCloser, error)
+	Rename(oldName, newName string) error
+	// 以 prefix 开头的文件名，按字典序排列
+	List(prefix string) ([]string, error)
+	// 删除文件，文件不存在时返回的错误满足 os.IsNotExist
+	Delete(name string) error
+}
+
+// 本地文件存储
+// 写入时先写到同目录下的临时文件，Close 时 fsync 后重命名为目标文件，读取方不会看到写了一半的文件
+type fileStorage struct {
+	dir string
+}
+
+func NewFileStorage(dir string) *fileStorage {
+	return &fileStorage{dir: dir}
+}
+
+func (s *fileStorage) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s *fileStorage) Create(name string) (io.WriteCloser, error) {
+	filename := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	fd, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: fd, name: filename}, nil
+}
+
+func (s *fileStorage) Open(name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+func (s *fileStorage) Rename(oldName, newName string) error {
+	return os.Rename(s.path(oldName), s.path(newName))
+}
+
+func (s *fileStorage) List(prefix string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(s.dir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filename == s.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(s.dir, filename)
+		if err != nil {
+			return err
+		}
+		if name = filepath.ToSlash(name); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (s *fileStorage) Delete(name string) error {
+	return os.Remove(s.path(name))
+}
+
+// 临时文件，Close 时替换目标文件
+type atomicFile struct {
+	*os.File
+	name string
+}
+
+func (f *atomicFile) Close() (err error) {
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.File.Name())
+		}
+	}()
+	if err = f.Chmod(0644); err != nil {
+		_ = f.File.Close()
+		return
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.File.Close()
+		return
+	}
+	if err = f.File.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(f.File.Name(), f.name); err != nil {
+		return
+	}
+	// 重命名写入目录后才算持久化，部分系统不支持对目录 fsync，忽略错误
+	if dir, e := os.Open(filepath.Dir(f.name)); e == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+	return nil
+}
+
+// 放弃写入，删除临时文件
+func (f *atomicFile) abort() error {
+	_ = f.File.Close()
+	return os.Remove(f.File.Name())
+}
+
+// 内存存储，适合测试或者由 http.Handler 直接提供sitemap
+type memoryStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+func NewMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		files: make(map[string][]byte),
+	}
+}
+
+func (s *memoryStorage) Create(name string) (io.WriteCloser, error) {
+	return &memoryFile{storage: s, name: name}, nil
+}
+
+func (s *memoryStorage) Open(name string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStorage) Rename(oldName, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[oldName]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrNotExist}
+	}
+	delete(s.files, oldName)
+	s.files[newName] = data
+	return nil
+}
+
+func (s *memoryStorage) List(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var names []string
+	for name := range s.files {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memoryStorage) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	delete(s.files, name)
+	return nil
+}
+
+// 写入内存，Close 时保存
+type memoryFile struct {
+	bytes.Buffer
+	storage *memoryStorage
+	name    string
+}
+
+func (f *memoryFile) abort() error {
+	return nil
+}
+
+func (f *memoryFile) Close() error {
+	f.storage.mu.Lock()
+	defer f.storage.mu.Unlock()
+	f.storage.files[f.name] = append([]byte(nil), f.Bytes()...)
+	return nil
+}
+
+// 写入失败时放弃已经写入的内容，不替换原来的文件
+type aborter interface {
+	abort() error
+}
+
+// 写入文件，compress 时以 gzip 压缩
+// 写入或压缩失败时，支持 abort 的存储会保留原来的文件
+func writeFile(storage Storage, name string, data []byte, compress bool) (err error) {
+	w, err := storage.Create(name)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if a, ok := w.(aborter); ok && err != nil {
+			_ = a.abort()
+			return
+		}
+		if e := w.Close(); err == nil {
+			err = e
+		}
+	}()
+	if !compress {
+		_, err = w.Write(data)
+		return
+	}
+	gw := gzip.NewWriter(w)
+	if _, err = gw.Write(data); err != nil {
+		return
+	}
+	return gw.Close()
+}
+
+// 读取文件的全部内容
+func readFile(storage Storage, name string) ([]byte, error) {
+	r, err := storage.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// 文件是否存在
+func exists(storage Storage, name string) bool {
+	r, err := storage.Open(name)
+	if err != nil {
+		return false
+	}
+	_ = r.Close()
+	return true
+}
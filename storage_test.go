@@ -0,0 +1,191 @@
+package gositemap
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// 各个 Storage 实现共同的行为
+func testStorage(t *testing.T, storage Storage) {
+	if err := writeFile(storage, "sitemap.xml", []byte("<urlset/>"), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(storage, "news/sitemap.xml", []byte("<urlset>news</urlset>"), false); err != nil {
+		t.Fatal(err)
+	}
+	// 覆盖已有文件时不能残留原来的内容
+	if err := writeFile(storage, "sitemap.xml", []byte("<a/>"), false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := readFile(storage, "sitemap.xml")
+	if err != nil || string(data) != "<a/>" {
+		t.Fatalf("unexpected content %q %v", data, err)
+	}
+	if err = storage.Rename("sitemap.xml", "sitemap-1.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = storage.Open("sitemap.xml"); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+	names, err := storage.List("")
+	if err != nil || strings.Join(names, " ") != "news/sitemap.xml sitemap-1.xml" {
+		t.Fatalf("unexpected list %v %v", names, err)
+	}
+	names, err = storage.List("news/")
+	if err != nil || strings.Join(names, " ") != "news/sitemap.xml" {
+		t.Fatalf("unexpected list %v %v", names, err)
+	}
+	if err = storage.Delete("sitemap-1.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.Delete("sitemap-1.xml"); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testStorage(t, NewFileStorage(dir))
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemoryStorage())
+}
+
+func TestSitemap_SetStorage(t *testing.T) {
+	storage := NewMemoryStorage()
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.SetStorage(storage)
+	st.SetCompress(true)
+	st.SetMaxLinks(2)
+	for _, loc := range []string{"/a", "/b", "/c"} {
+		st.AppendUrl(NewUrl().SetLoc(loc))
+	}
+	filename, err := st.Storage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := storage.List("")
+	if filename != "sitemap.xml" || strings.Join(names, " ") != "sitemap-1.xml.gz sitemap-2.xml.gz sitemap.xml" {
+		t.Fatalf("unexpected files %s %v", filename, names)
+	}
+	r, err := storage.Open("sitemap-2.xml.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard, _, err := Parse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shard.Token) != 1 || shard.Token[0].(*url).Loc != "https://www.douyacun.com/c" {
+		t.Fatalf("unexpected shard %+v", shard.Token)
+	}
+}
+
+func TestFileStorage_Atomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage := NewFileStorage(dir)
+	if err = writeFile(storage, "sitemap.xml", []byte("<urlset>old</urlset>"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close 之前读取到的仍然是原来的内容
+	w, err := storage.Create("sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("<urlset>new")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := readFile(storage, "sitemap.xml"); string(data) != "<urlset>old</urlset>" {
+		t.Fatalf("unexpected content before close %q", data)
+	}
+	// 放弃写入时保留原来的内容
+	if err = w.(aborter).abort(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := readFile(storage, "sitemap.xml"); string(data) != "<urlset>old</urlset>" {
+		t.Fatalf("unexpected content after abort %q", data)
+	}
+
+	if err = writeFile(storage, "sitemap.xml", []byte("<urlset>new</urlset>"), false); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := readFile(storage, "sitemap.xml"); string(data) != "<urlset>new</urlset>" {
+		t.Fatalf("unexpected content after close %q", data)
+	}
+	// 不残留临时文件
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("unexpected files %v %v", files, err)
+	}
+}
+
+// 记录文件发布的顺序
+type recordStorage struct {
+	*memoryStorage
+	published []string
+}
+
+type recordFile struct {
+	*memoryFile
+	storage *recordStorage
+}
+
+func (f *recordFile) Close() error {
+	f.storage.published = append(f.storage.published, f.name)
+	return f.memoryFile.Close()
+}
+
+func (s *recordStorage) Create(name string) (io.WriteCloser, error) {
+	return &recordFile{memoryFile: &memoryFile{storage: s.memoryStorage, name: name}, storage: s}, nil
+}
+
+func (s *recordStorage) Rename(oldName, newName string) error {
+	s.published = append(s.published, newName)
+	return s.memoryStorage.Rename(oldName, newName)
+}
+
+func TestSitemap_StoragePublishOrder(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		storage := &recordStorage{memoryStorage: NewMemoryStorage()}
+		st, sst := NewSiteMap(), NewStreamSiteMap()
+		for _, o := range []*options{st.options, sst.options} {
+			o.SetStorage(storage)
+			o.SetMaxLinks(2)
+		}
+		for _, loc := range []string{"/a", "/b", "/c", "/d", "/e"} {
+			if stream {
+				if err := sst.AppendUrl(NewUrl().SetLoc(loc)); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				st.AppendUrl(NewUrl().SetLoc(loc))
+			}
+		}
+		var err error
+		if stream {
+			_, err = sst.Close()
+		} else {
+			_, err = st.Storage()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(storage.published, " "); got != "sitemap-1.xml sitemap-2.xml sitemap-3.xml sitemap.xml" {
+			t.Fatalf("stream=%v: unexpected publish order %s", stream, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gositemap
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+	"time"
+)
+
+// 流式生成sitemap，每个网址写入后即释放，适合网址数量巨大的站点
+// 超过 maxLinks 或 maxBytes 时自动切换到下一个文件 sitemap-1.xml、sitemap-2.xml ...，Close 时生成 sitemapindex
+type streamSiteMap struct {
+	*options
+	enc   *encoder
+	w     io.WriteCloser
+	gw    *gzip.Writer
+	files []string
+	// 每个文件中最新的 lastmod
+	lastMods []time.Time
+}
+
+func NewStreamSiteMap() *streamSiteMap {
+	return &streamSiteMap{
+		options: NewOptions(),
+	}
+}
+
+// 设置了 SetCanonical 时规范化 loc，已经写入的网址无法合并，重复的网址需要在调用前去掉
+func (s *streamSiteMap) AppendUrl(url *url) error {
+	if !strings.HasPrefix(url.Loc, "http") {
+		url.Loc = s.absUrl(url.Loc)
+	}
+	// 在副本上修改 loc，调用方持有的网址不受影响
+	if loc := s.canonical.apply(url.Loc); loc != url.Loc {
+		canonical := *url
+		canonical.Loc = loc
+		url = &canonical
+	}
+	if s.enc == nil {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	err := s.enc.Encode(url)
+	if (err == TooMuchLinksError || err == TooLargeError) && s.enc.Links() > 0 {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+		err = s.enc.Encode(url)
+	}
+	return err
+}
+
+// 写入结束标签并关闭文件
+// 只生成了一个文件时重命名为 filename，否则以 filename 生成 sitemapindex
+func (s *streamSiteMap) Close() (filename string, err error) {
+	if s.enc == nil {
+		if err = s.rotate(); err != nil {
+			return
+		}
+	}
+	if err = s.closeFile(); err != nil {
+		return
+	}
+	if len(s.files) == 1 {
+		filename = s.outputFilename()
+		err = s.store().Rename(s.files[0], filename)
+		return
+	}
+	index := NewSiteMapIndex()
+	for i, file := range s.files {
+		index.Append(s.absUrl(file), s.lastMods[i])
+	}
+	filename = s.filename
+	err = index.Save(s.store(), filename)
+	return
+}
+
+// 关闭当前文件，打开下一个分片文件
+func (s *streamSiteMap) rotate() (err error) {
+	if s.enc != nil {
+		if err = s.closeFile(); err != nil {
+			return
+		}
+	}
+	if s.format != XmlFormat {
+		return StreamFormatError
+	}
+	filename := s.shardFilename(len(s.files) + 1)
+	if s.compress {
+		filename += ".gz"
+	}
+	if s.w, err = s.store().Create(filename); err != nil {
+		return
+	}
+	var w io.Writer = s.w
+	if s.compress {
+		s.gw = gzip.NewWriter(s.w)
+		w = s.gw
+	}
+	s.enc = NewEncoder(w, s.options)
+	s.files = append(s.files, filename)
+	return
+}
+
+func (s *streamSiteMap) closeFile() error {
+	s.lastMods = append(s.lastMods, s.enc.LastMod())
+	err := s.enc.Close()
+	if s.gw != nil {
+		if e := s.gw.Close(); err == nil {
+			err = e
+		}
+		s.gw = nil
+	}
+	// 写入失败时不发布这个文件
+	if a, ok := s.w.(aborter); ok && err != nil {
+		_ = a.abort()
+		return err
+	}
+	if e := s.w.Close(); err == nil {
+		err = e
+	}
+	return err
+}
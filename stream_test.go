@@ -0,0 +1,83 @@
+package gositemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestEncoder_Encode(t *testing.T) {
+	var buf bytes.Buffer
+	st := NewSiteMap()
+	st.SetPretty(true)
+	enc := NewEncoder(&buf, st.options)
+	for i := 0; i < 3; i++ {
+		u := NewUrl().SetLoc(fmt.Sprintf("https://www.douyacun.com/article%d.html", i)).SetChangefreq(Daily)
+		if err := enc.Encode(u); err != nil {
+			t.Fatal(err)
+		}
+		st.AppendUrl(u)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if enc.Size() != buf.Len() {
+		t.Fatalf("size %d, written %d", enc.Size(), buf.Len())
+	}
+	data, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 除了命名空间声明以外，与 ToXml 的输出一致
+	body := string(data[strings.Index(string(data), "<url>"):])
+	if !strings.HasSuffix(buf.String(), body) {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestStreamSiteMap_Close(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	st := NewStreamSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	st.SetPublicPath(dir)
+	st.SetCompress(true)
+	st.SetMaxLinks(2)
+	for i := 0; i < 5; i++ {
+		if err := st.AppendUrl(NewUrl().SetLoc(fmt.Sprintf("/article%d.html", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename, err := st.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != "sitemap.xml" {
+		t.Fatalf("unexpected index filename %s", filename)
+	}
+	fd, err := os.Open(path.Join(dir, "sitemap-3.xml.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	gr, err := gzip.NewReader(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "https://www.douyacun.com/article4.html") || !strings.HasSuffix(string(data), "</urlset>") {
+		t.Fatalf("unexpected shard:\n%s", data)
+	}
+}
@@ -33,8 +33,15 @@ func (f po) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
 	return xml.Attr{Name: name, Value: s}, nil
 }
 
+// <mobile:mobile/>，百度规范下可以带上 type
+type mobileMarker struct {
+	XMLName xml.Name   `xml:"mobile:mobile"`
+	Type    MobileType `xml:"type,attr,omitempty"`
+}
+
 type url struct {
 	*base
+	validation
 	XMLName    xml.Name   `xml:"url"`
 	Loc        string     `xml:"loc"`
 	LastMod    string     `xml:"lastmod,omitempty"`
@@ -74,7 +81,8 @@ func (u *url) SetChangefreq(freq ChangeFreq) *url {
 // 网页优先级
 func (u *url) SetPriority(priority float64) *url {
 	if priority < 0 || priority > 1 {
-		panic(InvalidPriorityError{"Valid values range from 0.0 to 1.0"})
+		u.addError("priority", priority, &InvalidPriorityError{"Valid values range from 0.0 to 1.0"})
+		return u
 	}
 	u.Priority = po(priority)
 	return u
@@ -87,12 +95,89 @@ func (u *url) AppendVideo(video *video) {
 }
 
 // 对于单个网页上的多个图片，每个 <url> 标记最多可包含 1000 个 <image:image> 标记。
-func (u *url) AppendImage(image *image) {
+// 缺少 image:loc 时返回 MissingFieldError，超过 1000 张时返回 TooManyImagesError，图片不会被添加
+func (u *url) AppendImage(image *image) error {
+	if image == nil || image.Loc == "" {
+		return &FieldError{Loc: u.Loc, Field: "image:loc", Err: MissingFieldError}
+	}
+	if n := u.images(); n >= MaxImagesPerUrl {
+		return &FieldError{Loc: u.Loc, Field: "image:image", Value: n + 1, Err: TooManyImagesError}
+	}
+	u.appendImage(image)
+	return nil
+}
+
+// 不检查数量和必填字段，解析已有的sitemap时使用，由 Validate 报告错误
+func (u *url) appendImage(image *image) {
 	u.setNs(ImageXmlNS)
 	u.Token = append(u.Token, image)
 }
 
+// 网址中图片的数量
+func (u *url) images() (n int) {
+	for _, token := range u.Token {
+		if _, ok := token.(*image); ok {
+			n++
+		}
+	}
+	return
+}
+
 func (u *url) AppendNews(news *news) {
 	u.setNs(NewsXmlNS)
 	u.Token = append(u.Token, news)
 }
+
+// 面向功能手机的网页，输出 <mobile:mobile/>
+func (u *url) SetMobile(mobile bool) *url {
+	if mobile {
+		return u.SetMobileType("")
+	}
+	for i, token := range u.Token {
+		if _, ok := token.(*mobileMarker); ok {
+			u.Token = append(u.Token[:i], u.Token[i+1:]...)
+			break
+		}
+	}
+	return u
+}
+
+// 百度移动适配的网页类型，输出 <mobile:mobile type="pc,mobile"/>，需要配合 BaiduProfile 使用
+func (u *url) SetMobileType(t MobileType) *url {
+	u.setNs(MobileXmlNS)
+	for _, token := range u.Token {
+		if m, ok := token.(*mobileMarker); ok {
+			m.Type = t
+			return u
+		}
+	}
+	u.Token = append(u.Token, &mobileMarker{Type: t})
+	return u
+}
+
+// 网页的其他语言版本，需要包括网页自身
+func (u *url) AppendAlternate(alternate *alternate) {
+	u.setNs(XhtmlXmlNS)
+	u.Token = append(u.Token, alternate)
+}
+
+// 校验网址及其图片、视频、新闻是否符合sitemap协议，返回 ValidationErrors，校验失败的网址可以跳过不添加到sitemap
+func (u *url) Validate() error {
+	return u.validate().err()
+}
+
+func (u *url) validate() ValidationErrors {
+	errs := append(ValidationErrors{}, u.validation.validate()...)
+	errs = append(errs, u.check()...)
+	for _, token := range u.Token {
+		if v, ok := token.(interface{ validate() ValidationErrors }); ok {
+			errs = append(errs, v.validate()...)
+		}
+	}
+	for i, err := range errs {
+		e := *err
+		e.Loc = u.Loc
+		errs[i] = &e
+	}
+	return errs
+}
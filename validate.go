@@ -0,0 +1,459 @@
+package gositemap
+
+import (
+	"bytes"
+	"errors"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxLocLength defines max length of loc
+	MaxLocLength = 2048
+	// MaxImagesPerUrl defines max images per url
+	MaxImagesPerUrl = 1000
+	// MaxNewsPerSitemap defines max news per sitemap
+	MaxNewsPerSitemap = 1000
+	// MaxNewsAge defines how long an article can stay in news sitemap
+	MaxNewsAge = 48 * time.Hour
+)
+
+var (
+	MissingFieldError      = errors.New("缺少必填字段")
+	InvalidLocError        = errors.New("网址必须是以http或https开头的绝对地址")
+	LocTooLongError        = errors.New("网址不能超过 2048 个字符")
+	UnescapedLocError      = errors.New("网址包含未编码的字符，需要进行URL编码")
+	DoubleEscapedLocError  = errors.New("网址中包含已转义的实体，输出时会被重复转义")
+	DifferentHostError     = errors.New("网址必须与sitemap位于同一个站点")
+	TooManyImagesError     = errors.New("每个网址最多包含 1000 张图片")
+	TooManyNewsError       = errors.New("每个新闻sitemap最多包含 1000 篇新闻")
+	NewsExpiredError       = errors.New("新闻发布时间超过 48 小时")
+	InvalidDateError       = errors.New("日期格式错误，需要使用W3C Datetime格式")
+	MissingVideoLocError   = errors.New("video:content_loc 和 video:player_loc 至少需要一个")
+	InvalidChangeFreqError = errors.New("changefreq 只能是 always、hourly、daily、weekly、monthly、yearly、never")
+	InvalidValueError      = errors.New("不支持的取值")
+	InvalidNamespaceError  = errors.New("命名空间错误，必须是 " + SitemapNamespace)
+	UnknownElementError    = errors.New("sitemap协议中没有该元素")
+	InvalidHreflangError   = errors.New("hreflang必须是 ISO 639-1 语言代码，可以加上 ISO 3166-1 地区代码，或者 x-default")
+	DuplicateHreflangError = errors.New("同一个网址中hreflang重复")
+)
+
+// 校验磁盘上的sitemap文件，支持 .xml.gz 压缩文件，根节点可以是 urlset 或 sitemapindex
+// 按照 sitemaps.org 0.9 以及 Google image/video/news 扩展的规则校验，违反规则时返回 ValidationErrors，
+// 文件无法读取或不是合法的xml时返回对应的错误
+// 文件中的网址必须与第一个网址位于同一站点
+func ValidateFile(filename string) error {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	data, err := readAll(fd)
+	if err != nil {
+		return err
+	}
+	root, err := rootElement(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	if root.Space != SitemapNamespace {
+		errs = append(errs, &FieldError{Loc: filename, Field: root.Local, Value: root.Space, Err: InvalidNamespaceError})
+	}
+	if len(data) > MaxSitemapBytes {
+		errs = append(errs, &FieldError{Loc: filename, Field: root.Local, Value: len(data), Err: TooLargeError})
+	}
+	st, index, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if st != nil {
+		if len(st.Token) > MaxSitemapLinks {
+			errs = append(errs, &FieldError{Loc: filename, Field: root.Local, Value: len(st.Token), Err: TooMuchLinksError})
+		}
+		if len(st.Token) > 0 {
+			if u, e := neturl.Parse(st.Token[0].(*url).Loc); e == nil {
+				st.SetDefaultHost(u.Scheme + "://" + u.Host)
+			}
+		}
+		err = st.Validate()
+	} else {
+		if len(index.SiteMap) > MaxSitemapLinks {
+			errs = append(errs, &FieldError{Loc: filename, Field: root.Local, Value: len(index.SiteMap), Err: TooMuchLinksError})
+		}
+		err = index.Validate()
+	}
+	if e, ok := err.(ValidationErrors); ok {
+		errs = append(errs, e...)
+	}
+	return errs.err()
+}
+
+// 校验sitemap中的所有网址：setter中记录的错误、网址及各扩展的协议规则、同一站点、新闻数量
+// 返回 ValidationErrors，每个错误都带有所属网址和字段
+func (s *sitemap) Validate() error {
+	s = s.snapshot()
+	var (
+		errs  ValidationErrors
+		host  string
+		count int
+	)
+	if u, err := neturl.Parse(s.defaultHost); err == nil {
+		host = u.Host
+	}
+	errs = append(errs, s.parseErrs...)
+	for _, token := range s.Token {
+		u := token.(*url)
+		errs = append(errs, u.validate()...)
+		// 缺少 loc 时 validate 已经报告 MissingFieldError
+		if loc, ok := absoluteLoc(u.Loc); !ok && u.Loc != "" {
+			errs = append(errs, &FieldError{Loc: u.Loc, Field: "loc", Value: u.Loc, Err: InvalidLocError})
+		} else if ok && host != "" && !strings.EqualFold(loc.Host, host) {
+			errs = append(errs, &FieldError{Loc: u.Loc, Field: "loc", Value: u.Loc, Err: DifferentHostError})
+		}
+		for _, token := range u.Token {
+			if _, ok := token.(*news); !ok {
+				continue
+			}
+			if count++; count == MaxNewsPerSitemap+1 {
+				errs = append(errs, &FieldError{Loc: u.Loc, Field: "news:news", Value: count, Err: TooManyNewsError})
+			}
+		}
+	}
+	return errs.err()
+}
+
+// 网址本身的规则，不依赖所在的sitemap
+func (u *url) check() ValidationErrors {
+	var (
+		v      validation
+		images int
+	)
+	if err := checkLoc(u.Loc); err != nil {
+		v.addError("loc", u.Loc, err)
+	}
+	v.date(field{"lastmod", u.LastMod})
+	switch u.ChangeFreq {
+	case "", Always, Hourly, Daily, Weekly, Monthly, Yearly, Never:
+	default:
+		v.addError("changefreq", u.ChangeFreq, InvalidChangeFreqError)
+	}
+	hreflangs := make(map[string]bool)
+	for _, token := range u.Token {
+		switch t := token.(type) {
+		case *image:
+			images++
+		case *video:
+			// uploader 的 info 必须与网址位于同一个网域
+			if t.Uploader != nil && t.Uploader.Info != "" && !sameHost(u.Loc, t.Uploader.Info) {
+				v.addError("video:uploader", t.Uploader.Info, UploaderInfoError)
+			}
+		case *alternate:
+			hreflang := strings.ToLower(t.Hreflang)
+			if hreflangs[hreflang] {
+				v.addError("xhtml:link", t.Hreflang, DuplicateHreflangError)
+			}
+			hreflangs[hreflang] = true
+		}
+	}
+	if images > MaxImagesPerUrl {
+		v.addError("image:image", images, TooManyImagesError)
+	}
+	return v.errs
+}
+
+func (i *image) validate() ValidationErrors {
+	var v validation
+	v.required(field{"image:loc", i.Loc})
+	return v.errs
+}
+
+func (a *alternate) validate() ValidationErrors {
+	var v validation
+	v.required(field{"xhtml:link", a.Href})
+	if err := checkLoc(a.Href); a.Href != "" && err != nil {
+		v.addError("xhtml:link", a.Href, err)
+	} else if _, ok := absoluteLoc(a.Href); a.Href != "" && !ok {
+		v.addError("xhtml:link", a.Href, InvalidLocError)
+	}
+	if !isHreflang(a.Hreflang) {
+		v.addError("xhtml:link", a.Hreflang, InvalidHreflangError)
+	}
+	return v.errs
+}
+
+// ISO 639-1 语言代码[-文字代码][-ISO 3166-1 地区代码]，如 en、en-GB、zh-Hans-CN，或者 x-default
+// 不支持 ISO 639-2 三个字母的语言代码
+func isHreflang(hreflang string) bool {
+	if strings.EqualFold(hreflang, XDefault) {
+		return true
+	}
+	parts := strings.Split(hreflang, "-")
+	if len(parts) > 3 || !isTwoLetterLanguage(parts[0]) {
+		return false
+	}
+	parts = parts[1:]
+	if len(parts) > 0 && len(parts[0]) == 4 {
+		for _, r := range parts[0] {
+			if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') {
+				return false
+			}
+		}
+		parts = parts[1:]
+	}
+	switch len(parts) {
+	case 0:
+		return true
+	case 1:
+		return isCountry(strings.ToUpper(parts[0]))
+	}
+	return false
+}
+
+func (v *video) validate() ValidationErrors {
+	c := validation{errs: append(ValidationErrors{}, v.validation.validate()...)}
+	c.required(
+		field{"video:thumbnail_loc", v.ThumbnailLoc},
+		field{"video:title", v.Title},
+		field{"video:description", v.Description},
+	)
+	if v.ContentLoc == "" && (v.PlayerLoc == nil || v.PlayerLoc.Content == "") {
+		c.addError("video:content_loc", v.ContentLoc, MissingVideoLocError)
+	}
+	// 解析得到的视频没有经过setter，这里按照xsd再校验一遍取值
+	if len(v.Description) > 2048 {
+		c.addError("video:description", v.Description, InvalidDescriptionError)
+	}
+	if v.Duration < 0 || v.Duration > 28800 {
+		c.addError("video:duration", v.Duration, InvalidDurationError)
+	}
+	if v.Rating < 0 || v.Rating > 5 {
+		c.addError("video:rating", v.Rating, InvalidRatingError)
+	}
+	c.date(
+		field{"video:expiration_date", v.ExpirationDate},
+		field{"video:publication_date", v.PublicationDate},
+	)
+	c.yesNo(
+		field{"video:family_friendly", v.FamilyFriendly},
+		field{"video:requires_subscription", v.RequiresSubscription},
+		field{"video:live", v.Live},
+	)
+	if v.PlayerLoc != nil {
+		c.yesNo(field{"video:player_loc", v.PlayerLoc.AllowEmbed})
+	}
+	if v.Restriction != nil {
+		c.relationship(field{"video:restriction", v.Restriction.Relationship})
+		for _, code := range strings.Fields(v.Restriction.Content) {
+			if !isCountry(code) {
+				c.addError("video:restriction", code, InvalidRestrictionError)
+			}
+		}
+	}
+	if v.Platform != nil {
+		c.relationship(field{"video:platform", v.Platform.Relationship})
+		for _, p := range strings.Fields(v.Platform.Content) {
+			if p := platform(p); p != Web && p != Mobile && p != TV {
+				c.addError("video:platform", p, InvalidValueError)
+			}
+		}
+	}
+	for _, p := range v.Price {
+		if !isCurrency(p.Currency) {
+			c.addError("video:price", p.Currency, InvalidCurrencyError)
+		}
+		if t := strings.ToLower(p.Type); t != "" && t != "rent" && t != "own" {
+			c.addError("video:price", p.Type, InvalidValueError)
+		}
+		if r := strings.ToLower(p.Resolution); r != "" && r != "hd" && r != "sd" {
+			c.addError("video:price", p.Resolution, InvalidValueError)
+		}
+	}
+	if len(v.Tag) > MaxVideoTags {
+		c.addError("video:tag", len(v.Tag), InvalidTagError)
+	}
+	if len([]rune(v.Category)) > 256 {
+		c.addError("video:category", v.Category, InvalidCategoryError)
+	}
+	if v.GalleryLoc != nil {
+		c.required(field{"video:gallery_loc", v.GalleryLoc.Content})
+	}
+	for _, segment := range v.ContentSegmentLoc {
+		c.required(field{"video:content_segment_loc", segment.Content})
+		if segment.Duration < 0 || segment.Duration > 28800 {
+			c.addError("video:content_segment_loc", segment.Duration, InvalidDurationError)
+		}
+	}
+	if v.Uploader != nil {
+		c.required(field{"video:uploader", v.Uploader.Content})
+		if len([]rune(v.Uploader.Content)) > 255 {
+			c.addError("video:uploader", v.Uploader.Content, InvalidUploaderError)
+		}
+	}
+	if v.Id != nil {
+		c.required(field{"video:id", v.Id.Content})
+		if !isVideoIdType(v.Id.Type) {
+			c.addError("video:id", v.Id.Type, InvalidValueError)
+		}
+	}
+	return c.errs
+}
+
+func (n *news) validate() ValidationErrors {
+	c := validation{errs: append(ValidationErrors{}, n.validation.validate()...)}
+	c.required(
+		field{"news:name", n.Name},
+		field{"news:language", n.Language},
+		field{"news:publication_date", n.PublicationDate},
+		field{"news:title", n.Title},
+	)
+	if n.Language != "" && !isLanguage(n.Language) {
+		c.addError("news:language", n.Language, InvalidLanguageError)
+	}
+	if n.PublicationDate != "" {
+		if date, err := parseW3CDate(n.PublicationDate); err != nil {
+			c.addError("news:publication_date", n.PublicationDate, InvalidDateError)
+		} else if time.Since(date) > MaxNewsAge {
+			c.addError("news:publication_date", n.PublicationDate, NewsExpiredError)
+		}
+	}
+	if n.Access != "" && n.Access != Subscription && n.Access != Registration {
+		c.addError("news:access", n.Access, InvalidValueError)
+	}
+	for _, genre := range splitList(n.Genres) {
+		if !isGenre(genre) {
+			c.addError("news:genres", genre, InvalidValueError)
+		}
+	}
+	tickers := splitList(n.StockTickers)
+	if len(tickers) > MaxStockTickers {
+		c.addError("news:stock_tickers", n.StockTickers, TooManyStockTickersError)
+	}
+	for _, ticker := range tickers {
+		if !stockTickerRegexp.MatchString(ticker) {
+			c.addError("news:stock_tickers", ticker, InvalidStockTickerError)
+		}
+	}
+	return c.errs
+}
+
+// 字段名称和取值
+type field struct {
+	name  string
+	value string
+}
+
+// 必填字段
+func (v *validation) required(fields ...field) {
+	for _, f := range fields {
+		if f.value == "" {
+			v.addError(f.name, f.value, MissingFieldError)
+		}
+	}
+}
+
+// W3C Datetime 格式的日期
+func (v *validation) date(fields ...field) {
+	for _, f := range fields {
+		if _, err := parseW3CDate(f.value); f.value != "" && err != nil {
+			v.addError(f.name, f.value, InvalidDateError)
+		}
+	}
+}
+
+// 只能是 yes 或 no
+func (v *validation) yesNo(fields ...field) {
+	for _, f := range fields {
+		if f.value != "" && f.value != "yes" && f.value != "no" {
+			v.addError(f.name, f.value, InvalidValueError)
+		}
+	}
+}
+
+// 只能是 allow 或 deny
+func (v *validation) relationship(fields ...field) {
+	for _, f := range fields {
+		if f.value != "allow" && f.value != "deny" {
+			v.addError(f.name, f.value, InvalidValueError)
+		}
+	}
+}
+
+// 校验sitemapindex：loc 必须是绝对地址，lastmod 为W3C Datetime格式
+func (s *siteMapIndex) Validate() error {
+	var errs ValidationErrors
+	for _, m := range s.SiteMap {
+		if err := checkLoc(m.Loc); err != nil {
+			errs = append(errs, &FieldError{Loc: m.Loc, Field: "loc", Value: m.Loc, Err: err})
+		} else if _, ok := absoluteLoc(m.Loc); !ok {
+			errs = append(errs, &FieldError{Loc: m.Loc, Field: "loc", Value: m.Loc, Err: InvalidLocError})
+		}
+		if _, err := parseW3CDate(m.LastMod); m.LastMod != "" && err != nil {
+			errs = append(errs, &FieldError{Loc: m.Loc, Field: "lastmod", Value: m.LastMod, Err: InvalidDateError})
+		}
+	}
+	return errs.err()
+}
+
+// 网址不能为空、不能超过 2048 个字符、需要进行URL编码
+func checkLoc(loc string) error {
+	switch {
+	case loc == "":
+		return MissingFieldError
+	case len(loc) > MaxLocLength:
+		return LocTooLongError
+	case strings.IndexFunc(loc, unescapedRune) >= 0:
+		return UnescapedLocError
+	case escapedEntity(loc):
+		return DoubleEscapedLocError
+	}
+	return nil
+}
+
+// 两个绝对地址的域名相同
+func sameHost(a, b string) bool {
+	ua, ok := absoluteLoc(a)
+	if !ok {
+		return false
+	}
+	ub, ok := absoluteLoc(b)
+	return ok && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// 以http或https开头的绝对地址
+func absoluteLoc(loc string) (*neturl.URL, bool) {
+	u, err := neturl.Parse(loc)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// W3C Datetime: https://www.w3.org/TR/NOTE-datetime
+func parseW3CDate(s string) (time.Time, error) {
+	layouts := []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// 网址中需要进行URL编码的字符
+func unescapedRune(r rune) bool {
+	return r <= 0x20 || r >= 0x7f || strings.ContainsRune("<>\"{}|\\^`", r)
+}
+
+func escapedEntity(loc string) bool {
+	for _, entity := range []string{"&amp;", "&lt;", "&gt;", "&quot;", "&apos;"} {
+		if strings.Contains(loc, entity) {
+			return true
+		}
+	}
+	return false
+}
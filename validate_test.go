@@ -0,0 +1,183 @@
+package gositemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSitemap_Validate(t *testing.T) {
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/ok.html"))
+	st.AppendUrl(NewUrl().SetLoc("https://www.example.com/other.html"))
+	st.AppendUrl(NewUrl().SetLoc("https:///file"))
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/" + strings.Repeat("a", MaxLocLength)))
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/a b.html"))
+	st.AppendUrl(NewUrl().SetLoc("https://www.douyacun.com/?a=1&amp;b=2"))
+
+	images := NewUrl().SetLoc("https://www.douyacun.com/images.html")
+	for i := 0; i <= MaxImagesPerUrl; i++ {
+		images.appendImage(NewImage().SetLoc(fmt.Sprintf("https://www.douyacun.com/%d.jpg", i)))
+	}
+	images.appendImage(NewImage())
+	st.AppendUrl(images)
+
+	videos := NewUrl().SetLoc("https://www.douyacun.com/video.html")
+	videos.AppendVideo(NewVideo().SetTitle("title"))
+	st.AppendUrl(videos)
+
+	old := NewUrl().SetLoc("https://www.douyacun.com/news.html")
+	old.AppendNews(NewNews().SetName("《示例时报》").SetLanguage("zh-cn").SetTitle("title").SetPublicationDate(time.Now().Add(-72 * time.Hour)))
+	st.AppendUrl(old)
+
+	err := st.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := []struct {
+		loc   string
+		field string
+		err   error
+	}{
+		{"https://www.example.com/other.html", "loc", DifferentHostError},
+		{"https:///file", "loc", InvalidLocError},
+		{"https://www.douyacun.com/" + strings.Repeat("a", MaxLocLength), "loc", LocTooLongError},
+		{"https://www.douyacun.com/a b.html", "loc", UnescapedLocError},
+		{"https://www.douyacun.com/?a=1&amp;b=2", "loc", DoubleEscapedLocError},
+		{"https://www.douyacun.com/images.html", "image:image", TooManyImagesError},
+		{"https://www.douyacun.com/images.html", "image:loc", MissingFieldError},
+		{"https://www.douyacun.com/video.html", "video:thumbnail_loc", MissingFieldError},
+		{"https://www.douyacun.com/video.html", "video:description", MissingFieldError},
+		{"https://www.douyacun.com/video.html", "video:content_loc", MissingVideoLocError},
+		{"https://www.douyacun.com/news.html", "news:publication_date", NewsExpiredError},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if errs[i].Loc != w.loc || errs[i].Field != w.field || !errors.Is(errs[i], w.err) {
+			t.Errorf("error %d: expected %s %s %v, got %v", i, w.loc, w.field, w.err, errs[i])
+		}
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:video="http://www.google.com/schemas/sitemap-video/1.1">
+  <url>
+    <loc>https://www.douyacun.com/</loc>
+    <lastmod>2020-04-19</lastmod>
+    <changefreq>sometimes</changefreq>
+    <priority>high</priority>
+  </url>
+  <url>
+    <loc>https://www.example.com/</loc>
+    <lastmod>19/04/2020</lastmod>
+    <title>unknown</title>
+    <video:video>
+      <video:thumbnail_loc>https://www.douyacun.com/123.jpg</video:thumbnail_loc>
+      <video:title>title</video:title>
+      <video:description>description</video:description>
+      <video:content_loc>https://www.douyacun.com/123.mp4</video:content_loc>
+      <video:family_friendly>maybe</video:family_friendly>
+    </video:video>
+  </url>
+</urlset>`
+	filename := path.Join(dir, "sitemap.xml")
+	if err = ioutil.WriteFile(filename, []byte(urlset), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var errs ValidationErrors
+	if err = ValidateFile(filename); !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := []struct {
+		field string
+		err   error
+	}{
+		{"priority", nil},
+		{"changefreq", InvalidChangeFreqError},
+		{"title", UnknownElementError},
+		{"lastmod", InvalidDateError},
+		{"video:family_friendly", InvalidValueError},
+		{"loc", DifferentHostError},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if errs[i].Field != w.field || (w.err != nil && !errors.Is(errs[i], w.err)) {
+			t.Errorf("error %d: expected %s %v, got %v", i, w.field, w.err, errs[i])
+		}
+	}
+
+	index := NewSiteMapIndex()
+	index.Append("https://www.douyacun.com/sitemap-1.xml")
+	index.SiteMap = append(index.SiteMap, Map{Loc: "sitemap-2.xml", LastMod: "yesterday"})
+	data, err := index.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(data)
+	gw.Close()
+	filename = path.Join(dir, "sitemap_index.xml.gz")
+	if err = ioutil.WriteFile(filename, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err = ValidateFile(filename); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+	if !errors.Is(errs[0], InvalidLocError) || !errors.Is(errs[1], InvalidDateError) {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+func TestValidateFile_Loc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gositemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 网址原样保存，不会以 defaultHost 补全
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <lastmod>2020-04-19</lastmod>
+  </url>
+  <url>
+    <loc>/relative</loc>
+  </url>
+  <sitemap>
+    <loc>https://www.douyacun.com/sitemap-1.xml</loc>
+  </sitemap>
+</urlset>`
+	filename := path.Join(dir, "sitemap.xml")
+	if err = ioutil.WriteFile(filename, []byte(urlset), 0666); err != nil {
+		t.Fatal(err)
+	}
+	err = ValidateFile(filename)
+	for _, want := range []error{MissingFieldError, InvalidLocError, UnknownElementError} {
+		if !hasFieldError(err, want) {
+			t.Fatalf("expect %v, got %v", want, err)
+		}
+	}
+}
@@ -14,8 +14,22 @@ var (
 	InvalidRestrictionError = errors.New("国家/地区代码不支持")
 	InvalidCurrencyError    = errors.New("无效币种")
 	InvalidTagError         = errors.New("最多允许使用 32 个标签")
+	InvalidCategoryError    = errors.New("视频分类不能超过 256 个字符")
+	InvalidUploaderError    = errors.New("视频上传者的名称不能超过 255 个字符")
+	UploaderInfoError       = errors.New("video:uploader 的 info 必须与网址位于同一个网域")
 )
 
+const (
+	// MaxVideoTags defines max <video:tag> per video
+	MaxVideoTags = 32
+)
+
+// ISO 3166 国家/地区代码
+var countries = []string{"AD", "AE", "AF", "AG", "AI", "AL", "AM", "AO", "AQ", "AR", "AS", "AT", "AU", "AW", "AX", "AZ", "BA", "BB", "BD", "BE", "BF", "BG", "BH", "BI", "BJ", "BL", "BM", "BN", "BO", "BQ", "BR", "BS", "BT", "BV", "BW", "BY", "BZ", "CA", "CC", "CD", "CF", "CG", "CH", "CI", "CK", "CL", "CM", "CN", "CO", "CR", "CU", "CV", "CW", "CX", "CY", "CZ", "DE", "DJ", "DK", "DM", "DO", "DZ", "EC", "EE", "EG", "EH", "ER", "ES", "ET", "FI", "FJ", "FK", "FM", "FO", "FR", "GA", "GB", "GD", "GE", "GF", "GG", "GH", "GI", "GL", "GM", "GN", "GP", "GQ", "GR", "GS", "GT", "GU", "GW", "GY", "HK", "HM", "HN", "HR", "HT", "HU", "ID", "IE", "IL", "IM", "IN", "IO", "IQ", "IR", "IS", "IT", "JE", "JM", "JO", "JP", "KE", "KG", "KH", "KI", "KM", "KN", "KP", "KR", "KW", "KY", "KZ", "LA", "LB", "LC", "LI", "LK", "LR", "LS", "LT", "LU", "LV", "LY", "MA", "MC", "MD", "ME", "MF", "MG", "MH", "MK", "ML", "MM", "MN", "MO", "MP", "MQ", "MR", "MS", "MT", "MU", "MV", "MW", "MX", "MY", "MZ", "NA", "NC", "NE", "NF", "NG", "NI", "NL", "NO", "NP", "NR", "NU", "NZ", "OM", "PA", "PE", "PF", "PG", "PH", "PK", "PL", "PM", "PN", "PR", "PS", "PT", "PW", "PY", "QA", "RE", "RO", "RS", "RU", "RW", "SA", "SB", "SC", "SD", "SE", "SG", "SH", "SI", "SJ", "SK", "SL", "SM", "SN", "SO", "SR", "SS", "ST", "SV", "SX", "SY", "SZ", "TC", "TD", "TF", "TG", "TH", "TJ", "TK", "TL", "TM", "TN", "TO", "TR", "TT", "TV", "TW", "TZ", "UA", "UG", "UM", "US", "UY", "UZ", "VA", "VC", "VE", "VG", "VI", "VN", "VU", "WF", "WS", "YE", "YT", "ZA", "ZM", "ZW"}
+
+// ISO 4217 货币代码
+var currencies = []string{"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN", "BAM", "BBD", "BDT", "BGN", "BHD", "BIF", "BMD", "BND", "BOB", "BOV", "BRL", "BSD", "BTN", "BWP", "BYN", "BZD", "CAD", "CDF", "CHE", "CHF", "CHW", "CLF", "CLP", "CNY", "COP", "COU", "CRC", "CUC", "CUP", "CVE", "CZK", "DJF", "DKK", "DOP", "DZD", "EGP", "ERN", "ETB", "EUR", "FJD", "FKP", "GBP", "GEL", "GHS", "GIP", "GMD", "GNF", "GTQ", "GYD", "HKD", "HNL", "HRK", "HTG", "HUF", "IDR", "ILS", "INR", "IQD", "IRR", "ISK", "JMD", "JOD", "JPY", "KES", "KGS", "KHR", "KMF", "KPW", "KRW", "KWD", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL", "LYD", "MAD", "MDL", "MGA", "MKD", "MMK", "MNT", "MOP", "MRU", "MUR", "MVR", "MWK", "MXN", "MXV", "MYR", "MZN", "NAD", "NGN", "NIO", "NOK", "NPR", "NZD", "OMR", "PAB", "PEN", "PGK", "PHP", "PKR", "PLN", "PYG", "QAR", "RON", "RSD", "RUB", "RWF", "SAR", "SBD", "SCR", "SDG", "SEK", "SGD", "SHP", "SLL", "SOS", "SRD", "SSP", "STN", "SVC", "SYP", "SZL", "THB", "TJS", "TMT", "TND", "TOP", "TRY", "TTD", "TWD", "TZS", "UAH", "UGX", "USD", "USN", "UYI", "UYU", "UYW", "UZS", "VES", "VND", "VUV", "WST", "XAF", "XAG", "XAU", "XBA", "XBB", "XBC", "XBD", "XCD", "XDR", "XOF", "XPD", "XPF", "XPT", "XSU", "XTS", "XUA", "XXX", "YER", "ZAR", "ZMW", "ZWL", "CNH", "GGP", "IMP", "JEP", "KID", "NIS", "NTD", "PRB", "SLS", "RMB", "TVD", "ZWB", "DASH", "ETH", "VTC", "BCH", "BTC", "XBT", "XLM", "XMR", "XRP", "ZEC", "LTC", "ADF", "ADP", "AFA", "AOK", "AON", "AOR", "ARL", "ARP", "ARA", "ATS", "AZM", "BAD", "BEF", "BGL", "BOP", "BRB", "BRC", "BRN", "BRE", "BRR", "BYB", "BYR", "CSD", "CSK", "CYP", "DDM", "DEM", "ECS", "ECV", "EEK", "ESA", "ESB", "ESP", "FIM", "FRF", "GNE", "GHC", "GQE", "GRD", "GWP", "HRD", "IEP", "ILP", "ILR", "ISJ", "ITL", "LAJ", "LTL", "LUF", "LVL", "MAF", "MCF", "MGF", "MKN", "MLF", "MVQ", "MRO", "MXP", "MZM", "MTL", "NIC", "NLG", "PEH", "PEI", "PLZ", "PTE", "ROL", "RUR", "SDD", "SDP", "SIT", "SKK", "SML", "SRG", "STD", "SUR", "TJR", "TMM", "TPE", "TRL", "UAK", "UGS", "USS", "UYP", "UYN", "VAL", "VEB", "VEF", "XEU", "XFO", "XFU", "YDD", "YUD", "YUN", "YUR", "YUO", "YUG", "YUM", "ZAL", "ZMK", "ZRZ", "ZRN", "ZWC", "ZWD", "ZWN", "ZWR", "ZWL",}
+
 type platform string
 
 const (
@@ -43,25 +57,47 @@ type Restriction struct {
 	Content      string   `xml:",chardata"`
 }
 
-// 是否在指定平台展示，web/mobile/tv
+// 是否在指定平台展示，多个平台以空格分隔，如 web mobile
 // Relationship
 // 	- allow: 允许展示
 //  - deny: 禁止展示
 type Platform struct {
 	XMLName      xml.Name `xml:"video:platform"`
 	Relationship string   `xml:"relationship,attr"`
-	Content      platform `xml:",chardata"`
+	Content      string   `xml:",chardata"`
 }
 
-// 采购价格
+// 采购价格，同一个视频可以有多个价格，如租用和购买、高清和标清
 type Price struct {
 	XMLName    xml.Name `xml:"video:price"`
-	Currency   string   `xml:"currency,attr"`   // 货币，https://en.wikipedia.org/wiki/ISO_4217
-	Type       string   `xml:"type,attr"`       // 采购方式, rent: 出租，own：拥有
-	Resolution string   `xml:"resolution,attr"` // 清晰度, hd,sd
+	Currency   string   `xml:"currency,attr"`             // 货币，https://en.wikipedia.org/wiki/ISO_4217
+	Type       string   `xml:"type,attr,omitempty"`       // 采购方式, rent: 出租，own：拥有
+	Resolution string   `xml:"resolution,attr,omitempty"` // 清晰度, hd,sd
 	Content    float64  `xml:",chardata"`
 }
 
+// 视频所属的合集页面，title 为合集的标题
+type GalleryLoc struct {
+	XMLName xml.Name `xml:"video:gallery_loc"`
+	Title   string   `xml:"title,attr,omitempty"`
+	Content string   `xml:",chardata"`
+}
+
+// 视频的一个片段，duration 为片段的时长（秒）
+type ContentSegmentLoc struct {
+	XMLName  xml.Name `xml:"video:content_segment_loc"`
+	Duration int      `xml:"duration,attr,omitempty"`
+	Content  string   `xml:",chardata"`
+}
+
+// 视频在节目数据库中的标识
+// type: tms:series、tms:program、rovi:series、rovi:program、freebase、url
+type VideoId struct {
+	XMLName xml.Name `xml:"video:id"`
+	Type    string   `xml:"type,attr"`
+	Content string   `xml:",chardata"`
+}
+
 // 视频上传者信息
 type Uploader struct {
 	XMLName xml.Name `xml:"video:uploader"`
@@ -69,27 +105,32 @@ type Uploader struct {
 	Content string   `xml:",chardata"`
 }
 
+// 字段的顺序与 sitemap-video/1.1 xsd 中的顺序一致
 type video struct {
+	validation
 	XMLName              xml.Name `xml:"video:video"`
-	ThumbnailLoc         string   `xml:"video:thumbnail_loc"` // 视频缩略图文件的网址
-	Title                string   `xml:"video:title"`         // 视频标题
-	Description          string   `xml:"video:description"`   // 视频的说明，不得超过 2048 个字符
-	ContentLoc           string   `xml:"video:content_loc"`   // 指向实际视频媒体文件的网址
+	ThumbnailLoc         string   `xml:"video:thumbnail_loc"`         // 视频缩略图文件的网址
+	Title                string   `xml:"video:title"`                 // 视频标题
+	Description          string   `xml:"video:description"`           // 视频的说明，不得超过 2048 个字符
+	ContentLoc           string   `xml:"video:content_loc,omitempty"` // 指向实际视频媒体文件的网址
+	ContentSegmentLoc    []*ContentSegmentLoc
 	PlayerLoc            *PlayerLoc
-	Duration             int     `xml:"video:duration,omitempty"`         // 视频的时长
-	ExpirationDate       string  `xml:"video:expiration_date,omitempty"`  // 视频的失效日期
-	Rating               float64 `xml:"video:rating,omitempty"`           // 视频评分
-	ViewCount            int     `xml:"video:view_count,omitempty"`       // 视频观看次数
-	PublicationDate      string  `xml:"video:publication_date,omitempty"` // 第一次发布视频的日期
-	FamilyFriendly       string  `xml:"video:family_friendly,omitempty"`  // yes/no 开启安全搜索或关闭的情况下播放。
+	Duration             int      `xml:"video:duration,omitempty"`         // 视频的时长
+	ExpirationDate       string   `xml:"video:expiration_date,omitempty"`  // 视频的失效日期
+	Rating               float64  `xml:"video:rating,omitempty"`           // 视频评分
+	ViewCount            int      `xml:"video:view_count,omitempty"`       // 视频观看次数
+	PublicationDate      string   `xml:"video:publication_date,omitempty"` // 第一次发布视频的日期
+	Tag                  []string `xml:"video:tag,omitempty"`              // 视频标签，每个tag一个标签，最多支持32个标签
+	Category             string   `xml:"video:category,omitempty"`         // 分类，不超过 256 个字符
+	FamilyFriendly       string   `xml:"video:family_friendly,omitempty"`  // yes/no 开启安全搜索或关闭的情况下播放。
 	Restriction          *Restriction
-	Platform             *Platform
-	Price                *Price
+	GalleryLoc           *GalleryLoc
+	Price                []*Price
 	RequiresSubscription string `xml:"video:requires_subscription,omitempty"` // 是否需要订阅（付费或免费）才能观看视频 yes/no
 	Uploader             *Uploader
+	Platform             *Platform
 	Live                 string `xml:"video:live,omitempty"`
-	Tag                  string `xml:"video:tag,omitempty"`      // 视频标签，建议短标签，每个tag一个标签，最多支持32个标签
-	Category             string `xml:"video:category,omitempty"` // 分类
+	Id                   *VideoId
 }
 
 func NewVideo() *video {
@@ -111,7 +152,8 @@ func (v *video) SetTitle(title string) *video {
 // 视频的说明。不得超过 2048 个字符
 func (v *video) SetDescription(description string) *video {
 	if len(description) > 2048 {
-		panic(InvalidDescriptionError)
+		v.addError("video:description", description, InvalidDescriptionError)
+		return v
 	}
 	v.Description = description
 	return v
@@ -138,8 +180,9 @@ func (v *video) SetPlayerLoc(loc string, embed bool) *video {
 
 // 视频的时长（以秒为单位）。值必须介于 1（含）和 28800（8 小时，含）之间
 func (v *video) SetDuration(duration time.Duration) *video {
-	if duration > 28800*time.Second {
-		panic(InvalidDurationError)
+	if duration < time.Second || duration > 28800*time.Second {
+		v.addError("video:duration", duration, InvalidDurationError)
+		return v
 	}
 	v.Duration = int(duration / time.Second)
 	return v
@@ -154,7 +197,8 @@ func (v *video) SetExpirationDate(date time.Time) *video {
 // 视频的评分。支持的值为介于 0.0（下限，含）到 5.0（上限，含）之间的浮点数
 func (v *video) SetRating(rating float64) *video {
 	if rating < 0 || rating > 5 {
-		panic(InvalidRatingError)
+		v.addError("video:rating", rating, InvalidRatingError)
+		return v
 	}
 	v.Rating = rating
 	return v
@@ -184,17 +228,15 @@ func (v *video) SetFamilyFriendly(yes bool) *video {
 
 // 是否在来自特定国家/地区的搜索结果中显示或隐藏您的视频
 func (v *video) SetRestriction(code []string, allow bool) *video {
-	all := []string{"AD", "AE", "AF", "AG", "AI", "AL", "AM", "AO", "AQ", "AR", "AS", "AT", "AU", "AW", "AX", "AZ", "BA", "BB", "BD", "BE", "BF", "BG", "BH", "BI", "BJ", "BL", "BM", "BN", "BO", "BQ", "BR", "BS", "BT", "BV", "BW", "BY", "BZ", "CA", "CC", "CD", "CF", "CG", "CH", "CI", "CK", "CL", "CM", "CN", "CO", "CR", "CU", "CV", "CW", "CX", "CY", "CZ", "DE", "DJ", "DK", "DM", "DO", "DZ", "EC", "EE", "EG", "EH", "ER", "ES", "ET", "FI", "FJ", "FK", "FM", "FO", "FR", "GA", "GB", "GD", "GE", "GF", "GG", "GH", "GI", "GL", "GM", "GN", "GP", "GQ", "GR", "GS", "GT", "GU", "GW", "GY", "HK", "HM", "HN", "HR", "HT", "HU", "ID", "IE", "IL", "IM", "IN", "IO", "IQ", "IR", "IS", "IT", "JE", "JM", "JO", "JP", "KE", "KG", "KH", "KI", "KM", "KN", "KP", "KR", "KW", "KY", "KZ", "LA", "LB", "LC", "LI", "LK", "LR", "LS", "LT", "LU", "LV", "LY", "MA", "MC", "MD", "ME", "MF", "MG", "MH", "MK", "ML", "MM", "MN", "MO", "MP", "MQ", "MR", "MS", "MT", "MU", "MV", "MW", "MX", "MY", "MZ", "NA", "NC", "NE", "NF", "NG", "NI", "NL", "NO", "NP", "NR", "NU", "NZ", "OM", "PA", "PE", "PF", "PG", "PH", "PK", "PL", "PM", "PN", "PR", "PS", "PT", "PW", "PY", "QA", "RE", "RO", "RS", "RU", "RW", "SA", "SB", "SC", "SD", "SE", "SG", "SH", "SI", "SJ", "SK", "SL", "SM", "SN", "SO", "SR", "SS", "ST", "SV", "SX", "SY", "SZ", "TC", "TD", "TF", "TG", "TH", "TJ", "TK", "TL", "TM", "TN", "TO", "TR", "TT", "TV", "TW", "TZ", "UA", "UG", "UM", "US", "UY", "UZ", "VA", "VC", "VE", "VG", "VI", "VN", "VU", "WF", "WS", "YE", "YT", "ZA", "ZM", "ZW"}
 	access := 0
-	for _, i := range all {
-		for _, j := range code {
-			if j == i {
-				access++
-			}
+	for _, j := range code {
+		if isCountry(j) {
+			access++
 		}
 	}
 	if access != len(code) {
-		panic(InvalidRestrictionError)
+		v.addError("video:restriction", code, InvalidRestrictionError)
+		return v
 	}
 	v.Restriction = &Restriction{
 		Relationship: "",
@@ -210,9 +252,22 @@ func (v *video) SetRestriction(code []string, allow bool) *video {
 
 // 是否在指定类型的平台上的搜索结果中显示或隐藏您的视频
 func (v *video) SetPlatForm(p platform, allow bool) *video {
+	return v.SetPlatforms([]platform{p}, allow)
+}
+
+// 是否在多个平台上显示或隐藏您的视频，如 allow web mobile
+func (v *video) SetPlatforms(platforms []platform, allow bool) *video {
+	list := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		if p != Web && p != Mobile && p != TV {
+			v.addError("video:platform", p, InvalidValueError)
+			return v
+		}
+		list = append(list, string(p))
+	}
 	v.Platform = &Platform{
 		Relationship: "",
-		Content:      p,
+		Content:      strings.Join(list, " "),
 	}
 	if allow {
 		v.Platform.Relationship = "allow"
@@ -222,33 +277,34 @@ func (v *video) SetPlatForm(p platform, allow bool) *video {
 	return v
 }
 
+// 只保留一个价格，见 AppendPrice
+func (v *video) SetPrice(price float64, currency string, own bool, hd bool) *video {
+	v.Price = nil
+	return v.AppendPrice(price, currency, own, hd)
+}
+
+// 添加一个价格，不同的采购方式、清晰度可以有不同的价格
 // currency 货币，https://en.wikipedia.org/wiki/ISO_4217
 // own: 采购方式, true 拥有 false 租用
 // hd: 清晰度, true 高清 false 标清
-func (v *video) SetPrice(price float64, currency string, own bool, hd bool) *video {
-	all := []string{"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN", "BAM", "BBD", "BDT", "BGN", "BHD", "BIF", "BMD", "BND", "BOB", "BOV", "BRL", "BSD", "BTN", "BWP", "BYN", "BZD", "CAD", "CDF", "CHE", "CHF", "CHW", "CLF", "CLP", "CNY", "COP", "COU", "CRC", "CUC", "CUP", "CVE", "CZK", "DJF", "DKK", "DOP", "DZD", "EGP", "ERN", "ETB", "EUR", "FJD", "FKP", "GBP", "GEL", "GHS", "GIP", "GMD", "GNF", "GTQ", "GYD", "HKD", "HNL", "HRK", "HTG", "HUF", "IDR", "ILS", "INR", "IQD", "IRR", "ISK", "JMD", "JOD", "JPY", "KES", "KGS", "KHR", "KMF", "KPW", "KRW", "KWD", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL", "LYD", "MAD", "MDL", "MGA", "MKD", "MMK", "MNT", "MOP", "MRU", "MUR", "MVR", "MWK", "MXN", "MXV", "MYR", "MZN", "NAD", "NGN", "NIO", "NOK", "NPR", "NZD", "OMR", "PAB", "PEN", "PGK", "PHP", "PKR", "PLN", "PYG", "QAR", "RON", "RSD", "RUB", "RWF", "SAR", "SBD", "SCR", "SDG", "SEK", "SGD", "SHP", "SLL", "SOS", "SRD", "SSP", "STN", "SVC", "SYP", "SZL", "THB", "TJS", "TMT", "TND", "TOP", "TRY", "TTD", "TWD", "TZS", "UAH", "UGX", "USD", "USN", "UYI", "UYU", "UYW", "UZS", "VES", "VND", "VUV", "WST", "XAF", "XAG", "XAU", "XBA", "XBB", "XBC", "XBD", "XCD", "XDR", "XOF", "XPD", "XPF", "XPT", "XSU", "XTS", "XUA", "XXX", "YER", "ZAR", "ZMW", "ZWL", "CNH", "GGP", "IMP", "JEP", "KID", "NIS", "NTD", "PRB", "SLS", "RMB", "TVD", "ZWB", "DASH", "ETH", "VTC", "BCH", "BTC", "XBT", "XLM", "XMR", "XRP", "ZEC", "LTC", "ADF", "ADP", "AFA", "AOK", "AON", "AOR", "ARL", "ARP", "ARA", "ATS", "AZM", "BAD", "BEF", "BGL", "BOP", "BRB", "BRC", "BRN", "BRE", "BRR", "BYB", "BYR", "CSD", "CSK", "CYP", "DDM", "DEM", "ECS", "ECV", "EEK", "ESA", "ESB", "ESP", "FIM", "FRF", "GNE", "GHC", "GQE", "GRD", "GWP", "HRD", "IEP", "ILP", "ILR", "ISJ", "ITL", "LAJ", "LTL", "LUF", "LVL", "MAF", "MCF", "MGF", "MKN", "MLF", "MVQ", "MRO", "MXP", "MZM", "MTL", "NIC", "NLG", "PEH", "PEI", "PLZ", "PTE", "ROL", "RUR", "SDD", "SDP", "SIT", "SKK", "SML", "SRG", "STD", "SUR", "TJR", "TMM", "TPE", "TRL", "UAK", "UGS", "USS", "UYP", "UYN", "VAL", "VEB", "VEF", "XEU", "XFO", "XFU", "YDD", "YUD", "YUN", "YUR", "YUO", "YUG", "YUM", "ZAL", "ZMK", "ZRZ", "ZRN", "ZWC", "ZWD", "ZWN", "ZWR", "ZWL",}
-	access := false
-	for _, i := range all {
-		if i == currency {
-			access = true
-			break
-		}
-	}
-	if !access {
-		panic(InvalidCurrencyError)
+func (v *video) AppendPrice(price float64, currency string, own bool, hd bool) *video {
+	if !isCurrency(currency) {
+		v.addError("video:price", currency, InvalidCurrencyError)
+		return v
 	}
-	v.Price = &Price{
+	p := &Price{
 		Currency:   currency,
 		Type:       "own",
 		Resolution: "hd",
 		Content:    price,
 	}
 	if !own {
-		v.Price.Resolution = "rent"
+		p.Type = "rent"
 	}
 	if !hd {
-		v.Price.Resolution = "sd"
+		p.Resolution = "sd"
 	}
+	v.Price = append(v.Price, p)
 	return v
 }
 
@@ -262,9 +318,13 @@ func (v *video) SetRequiresSubscription(subscription bool) *video {
 	return v
 }
 
-// uploader, 视频上传者的名称
-// info, 包含有关此上传者的其他信息的网页对应的网址, 该网址必须与 <loc> 标记位于同一个网域中
+// uploader, 视频上传者的名称，不超过 255 个字符
+// info, 包含有关此上传者的其他信息的网页对应的网址, 该网址必须与 <loc> 标记位于同一个网域中，在 Validate 时检查
 func (v *video) SetUploader(uploader, info string) *video {
+	if len([]rune(uploader)) > 255 {
+		v.addError("video:uploader", uploader, InvalidUploaderError)
+		return v
+	}
 	v.Uploader = &Uploader{
 		Info:    info,
 		Content: uploader,
@@ -282,17 +342,98 @@ func (v *video) SetLive(yes bool) *video {
 	return v
 }
 
-// 用于描述视频的任意字符串标记, 最多允许使用 32 个
+// 用于描述视频的任意字符串标记, 每个标记输出一个 <video:tag>，最多允许使用 32 个
 func (v *video) SetTag(tags []string) *video {
-	if len(tags) > 32 {
-		panic(InvalidTagError)
+	if len(tags) > MaxVideoTags {
+		v.addError("video:tag", tags, InvalidTagError)
+		return v
+	}
+	v.Tag = append([]string(nil), tags...)
+	return v
+}
+
+// 添加一个标记
+func (v *video) AppendTag(tag string) *video {
+	if len(v.Tag) >= MaxVideoTags {
+		v.addError("video:tag", tag, InvalidTagError)
+		return v
 	}
-	v.Tag = strings.Join(tags, " ")
+	v.Tag = append(v.Tag, tag)
 	return v
 }
 
-// 视频所属宽泛类别的简短说明
+// 视频所属宽泛类别的简短说明，不超过 256 个字符
 func (v *video) SetCategory(category string) *video {
+	if len([]rune(category)) > 256 {
+		v.addError("video:category", category, InvalidCategoryError)
+		return v
+	}
 	v.Category = category
 	return v
 }
+
+// 视频所属合集页面的网址，每个视频只能属于一个合集
+func (v *video) SetGalleryLoc(loc, title string) *video {
+	v.GalleryLoc = &GalleryLoc{
+		Title:   title,
+		Content: loc,
+	}
+	return v
+}
+
+// 添加视频的一个片段，duration 为片段的时长
+func (v *video) AppendContentSegmentLoc(loc string, duration time.Duration) *video {
+	if duration != 0 && (duration < time.Second || duration > 28800*time.Second) {
+		v.addError("video:content_segment_loc", duration, InvalidDurationError)
+		return v
+	}
+	v.ContentSegmentLoc = append(v.ContentSegmentLoc, &ContentSegmentLoc{
+		Duration: int(duration / time.Second),
+		Content:  loc,
+	})
+	return v
+}
+
+// 视频在节目数据库中的标识，idType 为 tms:series、tms:program、rovi:series、rovi:program、freebase 或 url
+func (v *video) SetId(idType, id string) *video {
+	if !isVideoIdType(idType) {
+		v.addError("video:id", idType, InvalidValueError)
+		return v
+	}
+	v.Id = &VideoId{
+		Type:    idType,
+		Content: id,
+	}
+	return v
+}
+
+// 校验视频的字段，thumbnail_loc、title、description 必填，content_loc 和 player_loc 至少需要一个
+func (v *video) Validate() error {
+	return v.validate().err()
+}
+
+func isCountry(code string) bool {
+	for _, c := range countries {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func isVideoIdType(idType string) bool {
+	switch idType {
+	case "tms:series", "tms:program", "rovi:series", "rovi:program", "freebase", "url":
+		return true
+	}
+	return false
+}
+
+func isCurrency(code string) bool {
+	for _, c := range currencies {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
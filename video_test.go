@@ -0,0 +1,125 @@
+package gositemap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVideo_MultiValuedFields(t *testing.T) {
+	video := NewVideo().
+		SetThumbnailLoc("https://www.douyacun.com/thumbs/123.jpg").
+		SetTitle("适合夏季的烧烤排餐").
+		SetDescription("小安教您如何每次都能烤出美味牛排").
+		SetContentLoc("https://www.douyacun.com/video123.mp4").
+		AppendContentSegmentLoc("https://www.douyacun.com/video123-1.mp4", 300*time.Second).
+		AppendContentSegmentLoc("https://www.douyacun.com/video123-2.mp4", 300*time.Second).
+		SetTag([]string{"牛排", "烧烤"}).
+		AppendTag("夏季").
+		SetCategory("烹饪").
+		SetGalleryLoc("https://www.douyacun.com/gallery/grill", "烧烤合集").
+		SetPrice(1.99, "EUR", false, false).
+		AppendPrice(6.99, "EUR", true, true).
+		SetUploader("GrillyMcGrillerson", "https://www.douyacun.com/users/grilly").
+		SetPlatforms([]platform{Web, Mobile}, true).
+		SetId("url", "https://www.douyacun.com/programs/grill")
+	if err := video.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	st := NewSiteMap()
+	st.SetDefaultHost("https://www.douyacun.com")
+	url := NewUrl().SetLoc("https://www.douyacun.com/videos/123")
+	url.AppendVideo(video)
+	st.AppendUrl(url)
+	data, err := st.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"<video:tag>牛排</video:tag><video:tag>烧烤</video:tag><video:tag>夏季</video:tag>",
+		`<video:price currency="EUR" type="rent" resolution="sd">1.99</video:price><video:price currency="EUR" type="own" resolution="hd">6.99</video:price>`,
+		`<video:platform relationship="allow">web mobile</video:platform>`,
+		`<video:gallery_loc title="烧烤合集">https://www.douyacun.com/gallery/grill</video:gallery_loc>`,
+		`<video:content_segment_loc duration="300">https://www.douyacun.com/video123-2.mp4</video:content_segment_loc>`,
+		`<video:id type="url">https://www.douyacun.com/programs/grill</video:id>`,
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Fatalf("missing %s in %s", s, data)
+		}
+	}
+	// xsd 中的顺序
+	if strings.Index(string(data), "video:content_segment_loc") > strings.Index(string(data), "video:tag") ||
+		strings.Index(string(data), "video:price") > strings.Index(string(data), "video:platform") {
+		t.Fatalf("unexpected element order %s", data)
+	}
+
+	parsed, err := ParseSiteMap(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.SetDefaultHost("https://www.douyacun.com")
+	if err = parsed.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := parsed.ToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Fatalf("round trip mismatch:\n%s\n%s", data, again)
+	}
+}
+
+func TestVideo_Validate(t *testing.T) {
+	video := NewVideo().
+		SetThumbnailLoc("https://www.douyacun.com/thumbs/123.jpg").
+		SetTitle("title").
+		SetDescription("description").
+		SetContentLoc("https://www.douyacun.com/video123.mp4").
+		SetUploader(strings.Repeat("u", 256), "").
+		SetCategory(strings.Repeat("c", 257)).
+		SetId("imdb", "tt0000001").
+		SetPlatforms([]platform{"watch"}, true)
+	for i := 0; i < MaxVideoTags+1; i++ {
+		video.AppendTag(fmt.Sprintf("tag%d", i))
+	}
+	err := video.Validate()
+	for _, want := range []error{InvalidUploaderError, InvalidCategoryError, InvalidValueError, InvalidTagError} {
+		if !hasFieldError(err, want) {
+			t.Fatalf("expect %v, got %v", want, err)
+		}
+	}
+	if len(video.Tag) != MaxVideoTags {
+		t.Fatalf("expect %d tags, got %d", MaxVideoTags, len(video.Tag))
+	}
+
+	// uploader 的 info 与网址不在同一个网域
+	video = NewVideo().
+		SetThumbnailLoc("https://www.douyacun.com/thumbs/123.jpg").
+		SetTitle("title").
+		SetDescription("description").
+		SetContentLoc("https://www.douyacun.com/video123.mp4").
+		SetUploader("douyacun", "https://www.example.com/users/douyacun")
+	url := NewUrl().SetLoc("https://www.douyacun.com/videos/123")
+	url.AppendVideo(video)
+	if err = url.Validate(); !hasFieldError(err, UploaderInfoError) {
+		t.Fatalf("expect UploaderInfoError, got %v", err)
+	}
+}
+
+// err 中是否有字段错误为 target
+func hasFieldError(err error, target error) bool {
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	for _, e := range errs {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}